@@ -9,6 +9,9 @@ import (
 	"github.com/jferrl/go-githubauth"
 	"github.com/pkg/errors"
 	"golang.org/x/oauth2"
+	"net/http"
+	"net/url"
+	"strings"
 )
 
 var (
@@ -18,14 +21,38 @@ var (
 // Checks4shell is the parent command structure holding the GitHub App credentials
 // and responsible for setting up a GitHub client for child command to use
 type Checks4shell struct {
-	Run                     run.Run        `cmd:"" help:"Runs the given command, updates the given Github Check Run"`
-	Version                 VersionCommand `cmd:"" help:"Shows the version of the command"`
-	GithubAppPrivateKey     []byte         `env:"CHECKS4SHELL_GITHUB_APP_PRIVATE_KEY" help:"Path to the private key file used to authenticate to the Github App" type:"filecontent"`
-	GithubAppID             int64          `env:"CHECKS4SHELL_GITHUB_APP_ID" help:"Github App ID"`
-	GithubAppInstallationId int64          `env:"CHECKS4SHELL_GITHUB_APP_INSTALLATION_ID" help:"Github App Installation ID"`
+	Run                     run.Run          `cmd:"" help:"Runs the given command, updates the given Github Check Run"`
+	Multi                   run.Multi        `cmd:"" help:"Runs the commands listed in a manifest concurrently, each updating its own Github Check Run"`
+	Queue                   run.Queue        `cmd:"" help:"Creates queued Github Check Runs to announce the checks coming up"`
+	Create                  run.Create       `cmd:"" help:"Creates a Github Check Run and keeps its state in a local file for the update and complete commands"`
+	Update                  run.Update       `cmd:"" help:"Appends output or annotations to the Github Check Run created by the create command"`
+	Complete                run.Complete     `cmd:"" help:"Completes the Github Check Run created by the create command with a conclusion"`
+	Replay                  run.Replay       `cmd:"" help:"Sends the payloads recorded by the run command with --record again"`
+	Flush                   run.Flush        `cmd:"" help:"Sends the last updates spooled by the run command with --spool-dir while GitHub couldn't be reached"`
+	MockServer              run.MockServer   `cmd:"" help:"Serves the check runs endpoints of the GitHub API from memory, to be used with --github-base-url"`
+	Serve                   run.Serve        `cmd:"" help:"Serves a webhook receiver running the checks again when they are rerequested"`
+	ServeActions            run.ServeActions `cmd:"" help:"Serves a webhook receiver running shell commands for the requested check run actions"`
+	Version                 VersionCommand   `cmd:"" help:"Shows the version of the command"`
+	GithubBaseURL           string           `env:"CHECKS4SHELL_GITHUB_BASE_URL" help:"Base URL of the GitHub API, e.g. the address of the mock-server command. GitHub App credentials are optional with it"`
+	GithubAppPrivateKey     []byte           `env:"CHECKS4SHELL_GITHUB_APP_PRIVATE_KEY" help:"Path to the private key file used to authenticate to the Github App" type:"filecontent"`
+	GithubAppID             int64            `env:"CHECKS4SHELL_GITHUB_APP_ID" help:"Github App ID"`
+	GithubAppInstallationId int64            `env:"CHECKS4SHELL_GITHUB_APP_INSTALLATION_ID" help:"Github App Installation ID"`
 }
 
 func (c *Checks4shell) AfterApply(ctx *kong.Context) error {
+	// an unauthenticated client is enough for a GitHub API other than the actual one, like the mock server
+	if c.GithubAppPrivateKey == nil && c.GithubBaseURL != "" {
+		githubClient, err := c.newGithubClient(nil)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		ctx.Bind(&run.Config{
+			ChecksService:   run.NewChecksService(githubClient),
+			IsAuthenticated: true,
+		})
+		return nil
+	}
+
 	// supplied an unauthenticated client when GitHub App credential is not supplied
 	// making it easy for local testing
 	if c.GithubAppPrivateKey == nil {
@@ -43,14 +70,32 @@ func (c *Checks4shell) AfterApply(ctx *kong.Context) error {
 
 	installationTokenSource := githubauth.NewInstallationTokenSource(c.GithubAppInstallationId, appTokenSource)
 	httpClient := oauth2.NewClient(context.Background(), installationTokenSource)
-	githubClient := github.NewClient(httpClient)
+	githubClient, err := c.newGithubClient(httpClient)
+	if err != nil {
+		return errors.WithStack(err)
+	}
 	ctx.Bind(&run.Config{
-		ChecksService:   githubClient.Checks,
+		ChecksService:   run.NewChecksService(githubClient),
 		IsAuthenticated: true,
 	})
 	return nil
 }
 
+// newGithubClient returns a GitHub client sending its requests to the base URL if one is given
+func (c *Checks4shell) newGithubClient(httpClient *http.Client) (*github.Client, error) {
+	client := github.NewClient(httpClient)
+	if c.GithubBaseURL == "" {
+		return client, nil
+	}
+
+	baseURL, err := url.Parse(strings.TrimSuffix(c.GithubBaseURL, "/") + "/")
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing the GitHub base URL")
+	}
+	client.BaseURL = baseURL
+	return client, nil
+}
+
 // VersionCommand is the struct for VersionCommand
 type VersionCommand struct {
 }
@@ -0,0 +1,125 @@
+package run
+
+import (
+	"context"
+	"github.com/google/go-github/v64/github"
+	"github.com/pkg/errors"
+	"time"
+)
+
+const (
+	// adaptiveBurstBytes is the output written between two updates bringing the frequency back to the fastest
+	adaptiveBurstBytes = 4096
+	// adaptiveLowRateDivisor makes the updates as slow as they go once less than a fifth of the rate limit is left
+	adaptiveLowRateDivisor = 5
+)
+
+// adaptiveUpdater updates the check run at a pace following the output and the rate limit left
+type adaptiveUpdater struct {
+	done chan error
+}
+
+// Wait returns once the updates stopped, with the error which stopped them, it implements quartz.Waiter
+func (u *adaptiveUpdater) Wait(...string) error {
+	return <-u.done
+}
+
+// startAdaptiveUpdates calls update at --min-update-frequency to begin with, and as soon as the output grew a lot since
+// the previous update, which is checked at --min-update-frequency. The interval doubles up to --max-update-frequency
+// while the output is quiet, and is stretched so as not to run out of the rate limit left before it resets. It stops
+// when the context is done or update fails
+func (r *Run) startAdaptiveUpdates(ctx context.Context, update func() error) *adaptiveUpdater {
+	u := &adaptiveUpdater{done: make(chan error, 1)}
+	go func() {
+		burst := r.clock.NewTicker(r.MinUpdateFrequency, "adaptive-update", "burst")
+		defer burst.Stop()
+
+		interval := r.MinUpdateFrequency
+		written := r.outputWritten()
+		last := r.clock.Now()
+		for {
+			timer := r.clock.NewTimer(interval, "adaptive-update")
+		wait:
+			for {
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					u.done <- errors.WithStack(ctx.Err())
+					return
+				case <-timer.C:
+					break wait
+				case <-burst.C:
+					// a burst of output is shown early, as long as the rate limit allows it
+					grown := r.outputWritten() - written
+					if grown >= adaptiveBurstBytes && r.clock.Since(last) >= r.nextUpdateInterval(interval, grown) {
+						timer.Stop()
+						break wait
+					}
+				}
+			}
+
+			err := update()
+			if err != nil {
+				u.done <- errors.WithStack(err)
+				return
+			}
+
+			previous := written
+			written = r.outputWritten()
+			last = r.clock.Now()
+			interval = r.nextUpdateInterval(interval, written-previous)
+		}
+	}()
+	return u
+}
+
+// nextUpdateInterval returns the interval until the next update from the current one and the output written since
+// the last update
+func (r *Run) nextUpdateInterval(current time.Duration, written int64) time.Duration {
+	next := current
+	switch {
+	case written >= adaptiveBurstBytes:
+		next = r.MinUpdateFrequency
+	case written == 0:
+		next = current * 2
+	}
+	next = min(max(next, r.MinUpdateFrequency), r.MaxUpdateFrequency)
+
+	r.resultLock.RLock()
+	rate := r.rate
+	r.resultLock.RUnlock()
+	if rate.Limit <= 0 {
+		return next
+	}
+
+	if rate.Remaining*adaptiveLowRateDivisor < rate.Limit {
+		return r.MaxUpdateFrequency
+	}
+
+	// spreads the requests left evenly until the limit resets
+	untilReset := rate.Reset.Sub(r.clock.Now())
+	if rate.Remaining > 0 && untilReset > 0 {
+		next = max(next, min(untilReset/time.Duration(rate.Remaining), r.MaxUpdateFrequency))
+	}
+	return next
+}
+
+// outputWritten returns the number of bytes of output written so far
+func (r *Run) outputWritten() int64 {
+	var written int64
+	for _, s := range r.screens() {
+		written += s.Written()
+	}
+	return written
+}
+
+// setRate records the rate limit of the GitHub API from the response to the last request
+func (r *Run) setRate(resp *github.Response) {
+	if resp == nil || resp.Rate.Limit <= 0 {
+		return
+	}
+
+	r.resultLock.Lock()
+	defer r.resultLock.Unlock()
+	r.rate = resp.Rate
+}
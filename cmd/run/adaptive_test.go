@@ -0,0 +1,117 @@
+package run
+
+import (
+	"context"
+	"github.com/google/go-github/v64/github"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestNextUpdateInterval(t *testing.T) {
+	t.Parallel()
+	r, clock := newRun(t, &runConfig{runId: 23})
+	r.MinUpdateFrequency = 2 * time.Second
+	r.MaxUpdateFrequency = time.Minute
+
+	// quiet output backs off up to the max
+	require.Equal(t, 4*time.Second, r.nextUpdateInterval(2*time.Second, 0))
+	require.Equal(t, time.Minute, r.nextUpdateInterval(40*time.Second, 0))
+	// some output keeps the pace, a burst goes back to the min
+	require.Equal(t, 16*time.Second, r.nextUpdateInterval(16*time.Second, 100))
+	require.Equal(t, 2*time.Second, r.nextUpdateInterval(16*time.Second, adaptiveBurstBytes))
+
+	// plenty of requests left
+	r.setRate(&github.Response{Rate: github.Rate{Limit: 5000, Remaining: 4000, Reset: github.Timestamp{Time: clock.Now().Add(time.Hour)}}})
+	require.Equal(t, 2*time.Second, r.nextUpdateInterval(16*time.Second, adaptiveBurstBytes))
+	// the requests left are spread until the reset
+	r.setRate(&github.Response{Rate: github.Rate{Limit: 5000, Remaining: 1200, Reset: github.Timestamp{Time: clock.Now().Add(time.Hour)}}})
+	require.Equal(t, 3*time.Second, r.nextUpdateInterval(16*time.Second, adaptiveBurstBytes))
+	// running low
+	r.setRate(&github.Response{Rate: github.Rate{Limit: 5000, Remaining: 900, Reset: github.Timestamp{Time: clock.Now().Add(time.Hour)}}})
+	require.Equal(t, time.Minute, r.nextUpdateInterval(2*time.Second, adaptiveBurstBytes))
+	// responses without a rate leave it as it is
+	r.setRate(&github.Response{})
+	require.Equal(t, time.Minute, r.nextUpdateInterval(2*time.Second, adaptiveBurstBytes))
+}
+
+func TestAdaptiveUpdates(t *testing.T) {
+	t.Parallel()
+	r, clock := newRun(t, &runConfig{runId: 23}, command(t, "capture-signal")...)
+	r.AdaptiveUpdates = true
+	r.MinUpdateFrequency = time.Second
+	r.MaxUpdateFrequency = 8 * time.Second
+
+	trap := clock.Trap().NewTimer("adaptive-update")
+	defer trap.Close()
+	done := make(chan error, 1)
+	go func() {
+		done <- r.Run(&Config{})
+	}()
+
+	// quiet, the interval doubles from the min up to the max, the output being checked every second meanwhile
+	for _, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 8 * time.Second} {
+		call, err := trap.Wait(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, expected, call.Duration)
+		call.Release()
+		for elapsed := time.Duration(0); elapsed < expected; elapsed += time.Second {
+			clock.Advance(time.Second).MustWait(context.Background())
+		}
+	}
+
+	r.sigChan <- os.Interrupt
+	require.Error(t, <-done)
+	// created, updated on each of the 5 timers and completed
+	require.Len(t, getCheckServiceOutFromRun(t, r).Calls(), 7)
+}
+
+func TestAdaptiveUpdatesBurst(t *testing.T) {
+	t.Parallel()
+	r, clock := newRun(t, &runConfig{runId: 23})
+	r.MinUpdateFrequency = time.Second
+	r.MaxUpdateFrequency = 8 * time.Second
+
+	trap := clock.Trap().NewTimer("adaptive-update")
+	defer trap.Close()
+	updates := make(chan struct{}, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	u := r.startAdaptiveUpdates(ctx, func() error {
+		updates <- struct{}{}
+		return nil
+	})
+
+	// quiet for the first update, the next one is due in 2 seconds
+	call, err := trap.Wait(context.Background())
+	require.NoError(t, err)
+	call.Release()
+	clock.Advance(time.Second).MustWait(context.Background())
+	<-updates
+	call, err = trap.Wait(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2*time.Second, call.Duration)
+	call.Release()
+
+	// a burst is shown on the next check rather than once the 2 seconds are over
+	_, err = r.screen.Write(make([]byte, adaptiveBurstBytes))
+	require.NoError(t, err)
+	clock.Advance(time.Second).MustWait(context.Background())
+	<-updates
+	call, err = trap.Wait(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, time.Second, call.Duration)
+	call.Release()
+
+	cancel()
+	require.ErrorIs(t, u.Wait(), context.Canceled)
+}
+
+func TestAdaptiveUpdatesValidation(t *testing.T) {
+	t.Parallel()
+	r, _ := newRun(t, &runConfig{runId: 23}, command(t, "echo")...)
+	r.AdaptiveUpdates = true
+	r.MinUpdateFrequency = time.Minute
+	r.MaxUpdateFrequency = time.Second
+	require.ErrorContains(t, r.Run(&Config{}), "--adaptive-updates requires")
+}
@@ -0,0 +1,126 @@
+package run
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"sync"
+)
+
+const (
+	outputFloodStop = "stop"
+	outputFloodKill = "kill"
+
+	floodStoppedFormat = "\n[log flood] the output exceeded %s, the rest of it is not shown\n"
+	floodKilledFormat  = "\n[log flood] the output exceeded %s, the command was killed\n"
+)
+
+// outputBudget is the bytes and lines budget of the output of the run, shared by its steps, closing flooded once the
+// output exceeded it
+type outputBudget struct {
+	maxBytes int64
+	maxLines int64
+	kill     bool
+	flooded  chan struct{}
+
+	lock    sync.Mutex
+	written int64
+	lines   int64
+	over    bool
+}
+
+// newOutputBudget returns the budget of --max-output-bytes and --max-output-lines, nil when there is none
+func (r *Run) newOutputBudget() *outputBudget {
+	if r.maxOutputBytes <= 0 && r.MaxOutputLines <= 0 {
+		return nil
+	}
+
+	return &outputBudget{
+		maxBytes: r.maxOutputBytes,
+		maxLines: r.MaxOutputLines,
+		kill:     r.OnMaxOutput == outputFloodKill,
+		flooded:  make(chan struct{}),
+	}
+}
+
+// killed tells whether the output exceeded the budget in kill mode, in which case no step runs after the one killed
+func (b *outputBudget) killed() bool {
+	if b == nil || !b.kill {
+		return false
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.over
+}
+
+// budgetWriter writes the output to the screen until it exceeds the budget, writing the log flood marker in place
+// of the rest of it
+type budgetWriter struct {
+	budget *outputBudget
+	out    io.Writer
+}
+
+func (w *budgetWriter) Write(p []byte) (int, error) {
+	b := w.budget
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.over {
+		return len(p), nil
+	}
+
+	allowed, limit := len(p), ""
+	if b.maxBytes > 0 && b.written+int64(allowed) > b.maxBytes {
+		allowed, limit = int(b.maxBytes-b.written), formatBytes(b.maxBytes)
+	}
+	if b.maxLines > 0 {
+		// what follows the last line allowed is over the budget
+		lines := b.lines
+		for i := 0; i < allowed; i++ {
+			if lines >= b.maxLines {
+				allowed, limit = i, fmt.Sprintf("%d lines", b.maxLines)
+				break
+			}
+			if p[i] == '\n' {
+				lines++
+			}
+		}
+		b.lines = lines
+	}
+
+	_, err := w.out.Write(p[:allowed])
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	b.written += int64(allowed)
+
+	if allowed < len(p) {
+		b.over = true
+		format := floodStoppedFormat
+		if b.kill {
+			format = floodKilledFormat
+		}
+		close(b.flooded)
+		_, err = fmt.Fprintf(w.out, format, limit)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// budgetedOutput returns the writer of the output to the screen enforcing the budget of the run, along with the
+// channel closed once the output exceeded it and the command is to be killed
+func (r *Run) budgetedOutput(screen *SyncScreen) (io.Writer, <-chan struct{}) {
+	if r.budget == nil {
+		return screen, nil
+	}
+
+	w := &budgetWriter{budget: r.budget, out: screen}
+	if !r.budget.kill {
+		return w, nil
+	}
+	return w, r.budget.flooded
+}
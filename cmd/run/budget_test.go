@@ -0,0 +1,67 @@
+package run
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestBudgetWriterBytes(t *testing.T) {
+	t.Parallel()
+	out := &bytes.Buffer{}
+	w := &budgetWriter{budget: &outputBudget{maxBytes: 10, flooded: make(chan struct{})}, out: out}
+
+	n, err := w.Write([]byte("12345"))
+	require.NoError(t, err)
+	require.Equal(t, 5, n)
+	n, err = w.Write([]byte("67890abc"))
+	require.NoError(t, err)
+	require.Equal(t, 8, n)
+	_, err = w.Write([]byte("more"))
+	require.NoError(t, err)
+
+	require.Equal(t, "1234567890\n[log flood] the output exceeded 10 B, the rest of it is not shown\n", out.String())
+	require.True(t, w.budget.over)
+	<-w.budget.flooded
+}
+
+func TestBudgetWriterLines(t *testing.T) {
+	t.Parallel()
+	out := &bytes.Buffer{}
+	w := &budgetWriter{budget: &outputBudget{maxLines: 2, kill: true, flooded: make(chan struct{})}, out: out}
+
+	_, err := w.Write([]byte("one\ntw"))
+	require.NoError(t, err)
+	require.False(t, w.budget.over)
+	_, err = w.Write([]byte("o\nthree\n"))
+	require.NoError(t, err)
+
+	require.Equal(t, "one\ntwo\n\n[log flood] the output exceeded 2 lines, the command was killed\n", out.String())
+	require.True(t, w.budget.over)
+}
+
+func TestMaxOutputLinesStop(t *testing.T) {
+	t.Parallel()
+	r, _ := newSampleRun(t, "seq", "100000")
+	r.MaxOutputLines = 3
+
+	require.NoError(t, r.Run(&Config{}))
+	completed := lastUpdate(t, r)
+	require.Equal(t, checksConclusionSuccess, completed.GetConclusion())
+	require.Equal(t, "```bash\n1\n2\n3\n\n[log flood] the output exceeded 3 lines, the rest of it is not shown\n```", completed.GetOutput().GetText())
+}
+
+func TestMaxOutputBytesKill(t *testing.T) {
+	t.Parallel()
+	r, _ := newSampleRun(t, "sh", "-c", "while true; do echo flood; done")
+	r.MaxOutputBytes = "1K"
+	r.OnMaxOutput = outputFloodKill
+
+	err := r.Run(&Config{})
+	require.ErrorContains(t, err, "output of the command exceeded its limit")
+	completed := lastUpdate(t, r)
+	require.Equal(t, checksConclusionFailure, completed.GetConclusion())
+	require.True(t, strings.HasSuffix(completed.GetOutput().GetText(), "[log flood] the output exceeded 1.0 KiB, the command was killed\n```"))
+	require.Equal(t, "signal: killed", r.exitStatus)
+}
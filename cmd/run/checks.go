@@ -8,12 +8,16 @@ import (
 	"github.com/pkg/errors"
 	"github.com/rivo/uniseg"
 	"io/fs"
+	"net/http"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
+	"time"
 )
 
 const (
+	checksStatusQueued       = "queued"
 	checksStatusInProgress   = "in_progress"
 	checksStatusCompleted    = "completed"
 	checksConclusionSuccess  = "success"
@@ -22,35 +26,76 @@ const (
 	truncatedTextReplacement = "[truncated]...\n\n"
 	outputLimit              = 65535
 	summaryLimit             = 65535
+	actionsLimit             = 3
+	actionLabelLimit         = 20
+	actionDescriptionLimit   = 40
+	actionIdentifierLimit    = 20
+	mediaTypeCheckRunsAPI    = "application/vnd.github.antiope-preview+json"
+	executionFooterFormat    = "\n\n---\n⏱️ Duration: `%s` · Exit status: `%s` · Host: `%s`"
+	exitStatusNotStarted     = "not started"
+	leftoversFooterFormat    = " · Leftover processes: `%s`"
 )
 
 // ChecksService is an interface abstraction for GitHub ChecksService
 type ChecksService interface {
 	CreateCheckRun(ctx context.Context, owner, repo string, opts github.CreateCheckRunOptions) (*github.CheckRun, *github.Response, error)
 	UpdateCheckRun(ctx context.Context, owner, repo string, checkRunID int64, opts github.UpdateCheckRunOptions) (*github.CheckRun, *github.Response, error)
+	ListCheckRunsForRef(ctx context.Context, owner, repo, ref string, opts *github.ListCheckRunsOptions) (*github.ListCheckRunsResults, *github.Response, error)
 }
 
-func (r *Run) getCheckRunOutput() (*github.CheckRunOutput, error) {
-	out := &github.CheckRunOutput{
-		Annotations: nil,
-		Images:      nil,
+// checkRunStarter is implemented by the ChecksService able to send started_at along with an update,
+// which github.UpdateCheckRunOptions has no field for
+type checkRunStarter interface {
+	StartCheckRun(ctx context.Context, owner, repo string, checkRunID int64, startedAt time.Time, opts github.UpdateCheckRunOptions) (*github.CheckRun, *github.Response, error)
+}
+
+// NewChecksService returns the ChecksService of the given GitHub client
+func NewChecksService(client *github.Client) ChecksService {
+	return &githubChecksService{
+		ChecksService: client.Checks,
+		client:        client,
 	}
+}
 
-	if r.Title != "" {
-		out.Title = github.String(r.Title)
+// githubChecksService adds StartCheckRun to the GitHub ChecksService
+type githubChecksService struct {
+	*github.ChecksService
+	client *github.Client
+}
+
+// StartCheckRun updates the check run the same way as UpdateCheckRun does, with started_at added to the payload
+func (s *githubChecksService) StartCheckRun(ctx context.Context, owner, repo string, checkRunID int64, startedAt time.Time, opts github.UpdateCheckRunOptions) (*github.CheckRun, *github.Response, error) {
+	body := struct {
+		github.UpdateCheckRunOptions
+		StartedAt github.Timestamp `json:"started_at"`
+	}{
+		UpdateCheckRunOptions: opts,
+		StartedAt:             github.Timestamp{Time: startedAt},
 	}
 
-	summary, err := r.getSummary()
+	req, err := s.client.NewRequest(http.MethodPatch, fmt.Sprintf("repos/%v/%v/check-runs/%v", owner, repo, checkRunID), body)
 	if err != nil {
-		return nil, errors.WithStack(err)
+		return nil, nil, errors.WithStack(err)
 	}
+	req.Header.Set("Accept", mediaTypeCheckRunsAPI)
 
-	out.Summary = github.String(summary)
+	checkRun := new(github.CheckRun)
+	resp, err := s.client.Do(ctx, req, checkRun)
+	if err != nil {
+		return nil, resp, errors.WithStack(err)
+	}
 
-	text := r.screen.ReadScreen()
-	if text != "" {
-		text = processOutput(text, r.SyntaxHighlight)
-		out.Text = github.String(text)
+	return checkRun, resp, nil
+}
+
+func (r *Run) getCheckRunOutput(conclusion string) (*github.CheckRunOutput, error) {
+	out := &github.CheckRunOutput{
+		Annotations: nil,
+		Images:      nil,
+	}
+
+	if r.Title != "" {
+		out.Title = github.String(r.getTitle())
 	}
 
 	annotations, err := r.getAnnotations()
@@ -59,6 +104,30 @@ func (r *Run) getCheckRunOutput() (*github.CheckRunOutput, error) {
 	}
 	out.Annotations = annotations
 
+	summary, err := r.getSummary(conclusion, len(annotations))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	out.Summary = github.String(summary)
+
+	if len(r.steps) > 0 {
+		out.Text = github.String(processSteps(r.steps, r.SyntaxHighlight, r.clock.Now(), r.getOutputLimit()))
+	} else {
+		input, limit := "", r.getOutputLimit()
+		if r.inputScreen != nil {
+			input, limit = processInput(r.inputScreen.ReadScreen(), r.SyntaxHighlight, limit)
+		}
+		// the output is truncated to its end anyway, only the end of the screen is read
+		text := r.screen.ReadTail(limit)
+		if text != "" {
+			text = processOutputWithLimit(text, r.SyntaxHighlight, limit)
+		}
+		if input+text != "" {
+			out.Text = github.String(input + text)
+		}
+	}
+
 	images, err := r.getImages()
 	if err != nil {
 		return nil, errors.Wrap(err, "error getting images")
@@ -68,11 +137,90 @@ func (r *Run) getCheckRunOutput() (*github.CheckRunOutput, error) {
 	return out, nil
 }
 
+// getOutputLimit returns the length the output text is truncated to, the GitHub limit unless set otherwise
+func (r *Run) getOutputLimit() int {
+	if r.outputLimit > 0 {
+		return r.outputLimit
+	}
+	return outputLimit
+}
+
+// getSummaryLimit returns the length the summary is truncated to, the GitHub limit unless set otherwise
+func (r *Run) getSummaryLimit() int {
+	if r.summaryLimit > 0 {
+		return r.summaryLimit
+	}
+	return summaryLimit
+}
+
+// attachCheckRun looks up the existing check run to keep updating instead of creating a new one
+func (r *Run) attachCheckRun() error {
+	if r.CheckRunID != 0 {
+		r.runId = r.CheckRunID
+		return nil
+	}
+
+	if !r.ReuseByExternalID || !r.isAuthenticated {
+		return nil
+	}
+
+	opts := &github.ListCheckRunsOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		result, resp, err := r.checksService.ListCheckRunsForRef(context.Background(), r.Owner, r.Repository, r.CommitSHA, opts)
+		if err != nil {
+			return errors.Wrap(err, "error listing check runs")
+		}
+
+		for _, checkRun := range result.CheckRuns {
+			if checkRun.GetExternalID() == r.ExternalID {
+				r.runId = checkRun.GetID()
+				return nil
+			}
+		}
+
+		if resp == nil || resp.NextPage == 0 {
+			return nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
 func (r *Run) createCheckRun(conclusion string) error {
+	err := r.attachCheckRun()
+	if err != nil {
+		return errors.Wrap(err, "error attaching to existing check run")
+	}
+
+	if r.runId != 0 {
+		// the existing check run starts from now on
+		r.pendingStart = conclusion == ""
+		return errors.WithStack(r.updateCheckRun(conclusion))
+	}
+
+	return errors.WithStack(r.sendCreateCheckRun(checksStatusInProgress, conclusion))
+}
+
+// queueCheckRun creates the check run in queued status ahead of the command starting
+func (r *Run) queueCheckRun() error {
+	err := r.attachCheckRun()
+	if err != nil {
+		return errors.Wrap(err, "error attaching to existing check run")
+	}
+
+	if r.runId != 0 {
+		return nil
+	}
+
+	return errors.WithStack(r.sendCreateCheckRun(checksStatusQueued, ""))
+}
+
+func (r *Run) sendCreateCheckRun(status string, conclusion string) error {
 	opt := github.CreateCheckRunOptions{
 		Name:    r.Name,
 		HeadSHA: r.CommitSHA,
-		Status:  github.String("in_progress"),
+		Status:  github.String(status),
 	}
 
 	if r.DetailsURL != "" {
@@ -83,26 +231,33 @@ func (r *Run) createCheckRun(conclusion string) error {
 		opt.ExternalID = github.String(r.ExternalID)
 	}
 
-	out, err := r.getCheckRunOutput()
+	if status != checksStatusQueued && !r.startedAt.IsZero() {
+		opt.StartedAt = &github.Timestamp{Time: r.startedAt}
+	}
+
+	out, err := r.getCheckRunOutput(conclusion)
 	if err != nil {
 		return errors.Wrap(err, "error getting output")
 	}
 
 	opt.Output = out
 
+	opt.Actions = r.actions
+
 	if conclusion != "" {
 		opt.Status = github.String(checksStatusCompleted)
 		opt.Conclusion = github.String(conclusion)
 		opt.CompletedAt = &github.Timestamp{Time: r.clock.Now()}
+		r.setConclusion(conclusion)
 	}
 
+	var sendErr error
 	if r.isAuthenticated {
-		checkRun, _, err := r.checksService.CreateCheckRun(context.Background(), r.Owner, r.Repository, opt)
-		if err != nil {
-			return errors.Wrap(err, "error creating check Run")
-		}
-
+		var checkRun *github.CheckRun
+		var resp *github.Response
+		checkRun, resp, sendErr = r.checksService.CreateCheckRun(context.Background(), r.Owner, r.Repository, opt)
 		r.runId = checkRun.GetID()
+		r.setRate(resp)
 	} else if r.Debug {
 		r.runId = -1
 		err = r.debug(opt)
@@ -111,10 +266,22 @@ func (r *Run) createCheckRun(conclusion string) error {
 		}
 	}
 
-	return nil
+	// the payload is recorded even when it failed to be sent
+	rec := &recordedPayload{Kind: recordKindCreate, Create: &opt}
+	err = r.record(rec)
+	if sendErr != nil {
+		return errors.WithStack(r.spoolOnFailure(errors.Wrap(sendErr, "error creating check Run"), rec, conclusion))
+	}
+
+	return errors.Wrap(err, "error recording check Run")
 }
 
 func (r *Run) updateCheckRun(conclusion string) error {
+	if r.isAuthenticated && r.runId == 0 && r.SpoolDir != "" {
+		// the creation failed while offline, try again
+		return errors.WithStack(r.sendCreateCheckRun(checksStatusInProgress, conclusion))
+	}
+
 	opt := github.UpdateCheckRunOptions{
 		Name:        r.Name,
 		Status:      nil,
@@ -132,7 +299,7 @@ func (r *Run) updateCheckRun(conclusion string) error {
 		opt.ExternalID = github.String(r.ExternalID)
 	}
 
-	out, err := r.getCheckRunOutput()
+	out, err := r.getCheckRunOutput(conclusion)
 	if err != nil {
 		return errors.Wrap(err, "error getting output")
 	}
@@ -140,16 +307,35 @@ func (r *Run) updateCheckRun(conclusion string) error {
 	opt.Output = out
 	opt.Status = github.String(checksStatusInProgress)
 
+	opt.Actions = r.actions
+
 	if conclusion != "" {
 		opt.Status = github.String(checksStatusCompleted)
 		opt.Conclusion = github.String(conclusion)
 		opt.CompletedAt = &github.Timestamp{Time: r.clock.Now()}
+		r.setConclusion(conclusion)
 	}
 
-	if r.isAuthenticated {
-		_, _, err = r.checksService.UpdateCheckRun(context.Background(), r.Owner, r.Repository, r.runId, opt)
-		if err != nil {
-			return errors.Wrapf(err, "error updating check Run %d", r.runId)
+	rec := &recordedPayload{Kind: recordKindUpdate, Update: &opt}
+	if r.pendingStart {
+		startedAt := r.startedAt
+		rec.StartedAt = &startedAt
+	}
+
+	var sendErr error
+	var resp *github.Response
+	starter, canStart := r.checksService.(checkRunStarter)
+	if r.isAuthenticated && r.pendingStart && canStart {
+		_, resp, sendErr = starter.StartCheckRun(context.Background(), r.Owner, r.Repository, r.runId, r.startedAt, opt)
+		if sendErr != nil {
+			sendErr = errors.Wrapf(sendErr, "error starting check Run %d", r.runId)
+		} else {
+			r.pendingStart = false
+		}
+	} else if r.isAuthenticated {
+		_, resp, sendErr = r.checksService.UpdateCheckRun(context.Background(), r.Owner, r.Repository, r.runId, opt)
+		if sendErr != nil {
+			sendErr = errors.Wrapf(sendErr, "error updating check Run %d", r.runId)
 		}
 	} else if r.Debug {
 		err = r.debug(opt)
@@ -158,7 +344,15 @@ func (r *Run) updateCheckRun(conclusion string) error {
 		}
 	}
 
-	return nil
+	r.setRate(resp)
+
+	// the payload is recorded even when it failed to be sent
+	err = r.record(rec)
+	if sendErr != nil {
+		return errors.WithStack(r.spoolOnFailure(sendErr, rec, conclusion))
+	}
+
+	return errors.Wrap(err, "error recording check Run")
 }
 
 func (r *Run) debug(payload any) error {
@@ -182,18 +376,53 @@ func marshal(v any) (string, error) {
 	return string(o), nil
 }
 
-func (r *Run) getSummary() (string, error) {
-	_, err := os.Stat(r.Summary)
-	if err != nil {
-		return processSummary(r.Summary), nil
-	}
+func (r *Run) getSummary(conclusion string, annotationCount int) (string, error) {
+	footer := r.executionFooter()
 
-	content, err := os.ReadFile(r.Summary)
+	summary, err := readTextOrFile(r.Summary)
 	if err != nil {
 		return "", errors.Wrap(err, "error reading summary file")
 	}
 
-	return processSummary(string(content)), nil
+	tmpl := r.summaryTemplate
+	if conclusion != "" && r.finalSummaryTemplate != nil {
+		tmpl = r.finalSummaryTemplate
+	}
+	if tmpl != nil {
+		summary, err = r.renderSummary(tmpl, summary, conclusion, annotationCount)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+	}
+
+	return processSummaryWithFooter(r.masker.mask(summary), footer, r.getSummaryLimit()), nil
+}
+
+// executionFooter returns the duration, exit status and host of the execution once it finished
+func (r *Run) executionFooter() string {
+	if r.NoExecutionFooter {
+		return ""
+	}
+
+	r.resultLock.RLock()
+	defer r.resultLock.RUnlock()
+	if r.finishedAt.IsZero() {
+		return ""
+	}
+
+	footer := fmt.Sprintf(executionFooterFormat, formatDuration(r.finishedAt.Sub(r.startedAt)), r.exitStatus, hostname())
+	if r.leftovers != "" {
+		footer += fmt.Sprintf(leftoversFooterFormat, r.leftovers)
+	}
+	return footer + r.usageFooter()
+}
+
+func hostname() string {
+	host, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	return host
 }
 
 func readFromDirectory[T any](dir string) ([]*T, error) {
@@ -214,7 +443,7 @@ func readFromDirectory[T any](dir string) ([]*T, error) {
 			return errors.Wrap(err, "error reading file")
 		}
 
-		var item *T
+		item := new(T)
 		err = json.Unmarshal(content, item)
 		if err != nil {
 			return errors.Wrap(err, "error parsing file")
@@ -238,14 +467,92 @@ func (r *Run) getImages() ([]*github.CheckRunImage, error) {
 	return readFromDirectory[github.CheckRunImage](r.Images)
 }
 
+// getActions returns the action buttons given by flags followed by the ones in the actions file
+func (r *Run) getActions() ([]*github.CheckRunAction, error) {
+	var actions []*github.CheckRunAction
+	for _, a := range r.Action {
+		parts := strings.SplitN(a, ":", 3)
+		if len(parts) != 3 {
+			return nil, errors.Errorf("action %q is not in the form of identifier:label:description", a)
+		}
+		actions = append(actions, &github.CheckRunAction{
+			Identifier:  parts[0],
+			Label:       parts[1],
+			Description: parts[2],
+		})
+	}
+
+	if r.ActionsFile != "" {
+		content, err := os.ReadFile(r.ActionsFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "error reading actions file")
+		}
+
+		var fromFile []*github.CheckRunAction
+		err = json.Unmarshal(content, &fromFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "error parsing actions file")
+		}
+		actions = append(actions, fromFile...)
+	}
+
+	if len(actions) > actionsLimit {
+		return nil, errors.Errorf("at most %d actions are allowed, got %d", actionsLimit, len(actions))
+	}
+
+	for _, a := range actions {
+		err := validateAction(a)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	return actions, nil
+}
+
+func validateAction(a *github.CheckRunAction) error {
+	fields := []struct {
+		name  string
+		value string
+		limit int
+	}{
+		{"identifier", a.Identifier, actionIdentifierLimit},
+		{"label", a.Label, actionLabelLimit},
+		{"description", a.Description, actionDescriptionLimit},
+	}
+	for _, f := range fields {
+		if f.value == "" {
+			return errors.Errorf("action %s is required", f.name)
+		}
+		if uniseg.GraphemeClusterCount(f.value) > f.limit {
+			return errors.Errorf("action %s %q is longer than %d characters", f.name, f.value, f.limit)
+		}
+	}
+	return nil
+}
+
 func processSummary(summary string) string {
-	return truncateOutput(summary, summaryLimit)
+	return processSummaryWithFooter(summary, "", summaryLimit)
+}
+
+// processSummaryWithFooter appends the footer to the summary, truncating the summary to leave room for it within the limit
+func processSummaryWithFooter(summary string, footer string, limit int) string {
+	// a footer too long for the limit is truncated along with the summary
+	if len(footer) >= limit {
+		return truncateOutput(summary+footer, limit)
+	}
+	return truncateOutput(summary, limit-len(footer)) + footer
 }
 
 // processOutput wraps the output in a code block
 func processOutput(text string, highlight string) string {
+	return processOutputWithLimit(text, highlight, outputLimit)
+}
+
+// processOutputWithLimit wraps the output in a code block no longer than the given limit
+func processOutputWithLimit(text string, highlight string, limit int) string {
 	formatLen := len(outputFormat) - 4 + len(highlight)
-	text = truncateOutput(text, outputLimit-formatLen)
+	text = truncateOutput(text, limit-formatLen)
 	return fmt.Sprintf(outputFormat, highlight, text)
 }
 
@@ -257,6 +564,10 @@ func truncateOutput(input string, limit int) string {
 
 	replacementTextLen := len(truncatedTextReplacement)
 	inputLimit := limit - replacementTextLen
+	// the limit left by the formatting, the steps or the input section can be too small for anything
+	if inputLimit <= 0 {
+		return ""
+	}
 
 	// start point calculated backward to the input limit
 	// and also make it 4 bytes further just to cater for the
@@ -4,12 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/google/go-github/v64/github"
+	"io"
 	"os"
 	"os/signal"
 	"path"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -49,6 +51,8 @@ var commands = map[string]func(args ...string) *errExitCode{
 	"cat-big-uni":     cmdCatBigUnicode,
 	"repeat-summary":  cmdRepeatSummary,
 	"capture-signal":  cmdCaptureSignal,
+	"upper":           cmdUpper,
+	"ignore-signals":  cmdIgnoreSignals,
 }
 
 // command returns the command executable that redirects back to the commands defined
@@ -70,6 +74,25 @@ func cmdEcho(args ...string) *errExitCode {
 	return nil
 }
 
+// cmdIgnoreSignals prints ready once it ignores the interrupt and termination signals
+func cmdIgnoreSignals(_ ...string) *errExitCode {
+	signal.Ignore(os.Interrupt, syscall.SIGTERM)
+	fmt.Println("ready")
+	time.Sleep(time.Minute)
+	return nil
+}
+
+// cmdUpper prints out its input in upper case
+func cmdUpper(_ ...string) *errExitCode {
+	input, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return &errExitCode{code: 1, error: err.Error()}
+	}
+
+	fmt.Print(strings.ToUpper(string(input)))
+	return nil
+}
+
 // cmdPrintWithSleep prints out its arguments in lines with 10ms time gap in between
 func cmdPrintWithSleep(args ...string) *errExitCode {
 	for _, arg := range args {
@@ -244,9 +267,11 @@ func cmdRepeatSummary(args ...string) *errExitCode {
 	return nil
 }
 
+// cmdCaptureSignal prints ready once it listens to the signals, and exits on the first one received
 func cmdCaptureSignal(_ ...string) *errExitCode {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan)
+	fmt.Println("ready")
 
 	s := <-sigChan
 
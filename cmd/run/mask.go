@@ -0,0 +1,228 @@
+package run
+
+import (
+	"bytes"
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	maskReplacement = "***"
+	// maskBufferLimit is the length of an unfinished line held back before it is masked and written anyway
+	maskBufferLimit = 4096
+)
+
+// maskDetector detects a kind of secret, its pattern being only tried on the text containing one of its hints
+type maskDetector struct {
+	hints   []string
+	pattern *regexp.Regexp
+}
+
+var (
+	// builtinMaskDetectors detect the secrets masked by default, the first group of the pattern when there
+	// is one is kept in front of the mask
+	builtinMaskDetectors = []*maskDetector{
+		// GitHub tokens
+		{
+			hints:   []string{"gh", "github_pat_"},
+			pattern: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{22,255})\b`),
+		},
+		// AWS access key IDs
+		{
+			hints:   []string{"AKIA", "ASIA"},
+			pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[A-Z0-9]{16}\b`),
+		},
+		// AWS secret access keys
+		{
+			hints:   []string{"ccess_key", "CCESS_KEY", "ccess_Key"},
+			pattern: regexp.MustCompile(`(?i)(aws_secret_access_key["']?\s*[=:]\s*["']?)[A-Za-z0-9/+=]{40}`),
+		},
+		// private keys within a single piece of text
+		{
+			hints:   []string{"-----BEGIN"},
+			pattern: regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z0-9 ]*PRIVATE KEY-----`),
+		},
+	}
+	pemBeginPattern = regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----`)
+	pemEndPattern   = regexp.MustCompile(`-----END [A-Z0-9 ]*PRIVATE KEY-----`)
+)
+
+// mask replaces the secrets detected in the text
+func (d *maskDetector) mask(text string) string {
+	hinted := false
+	for _, h := range d.hints {
+		if strings.Contains(text, h) {
+			hinted = true
+			break
+		}
+	}
+	if !hinted {
+		return text
+	}
+
+	if d.pattern.NumSubexp() > 0 {
+		return d.pattern.ReplaceAllString(text, "${1}"+maskReplacement)
+	}
+	return d.pattern.ReplaceAllLiteralString(text, maskReplacement)
+}
+
+// MaskFlags holds the flags of the secrets masked in the output and the summary
+type MaskFlags struct {
+	Mask            []string `env:"CHECKS4SHELL_MASK" sep:"none" help:"Value replaced with *** in the output and the summary, can be repeated"`
+	MaskEnv         []string `env:"CHECKS4SHELL_MASK_ENV" help:"Name of an environment variable whose value is replaced with *** in the output and the summary, can be repeated"`
+	NoMaskDetectors bool     `env:"CHECKS4SHELL_NO_MASK_DETECTORS" help:"Do not mask the GitHub tokens, AWS keys and private keys detected in the output and the summary"`
+}
+
+// newMasker returns the masker of the secrets given by the flags
+func (f *MaskFlags) newMasker() *masker {
+	return newMasker(f.Mask, f.MaskEnv, !f.NoMaskDetectors)
+}
+
+// masker replaces the secrets with ***
+type masker struct {
+	values    []string
+	detectors []*maskDetector
+}
+
+// newMasker returns a masker of the values given, the values of the environment variables named,
+// and the built-in detectors unless disabled. It returns nil when there is nothing to mask
+func newMasker(values []string, envNames []string, detectors bool) *masker {
+	m := &masker{}
+	for _, v := range values {
+		if v != "" {
+			m.values = append(m.values, v)
+		}
+	}
+	for _, name := range envNames {
+		if v := os.Getenv(name); v != "" {
+			m.values = append(m.values, v)
+		}
+	}
+	// the longest values first so that a value containing another one is masked as a whole
+	sort.Slice(m.values, func(i, j int) bool {
+		return len(m.values[i]) > len(m.values[j])
+	})
+
+	if detectors {
+		m.detectors = builtinMaskDetectors
+	}
+
+	if len(m.values) == 0 && len(m.detectors) == 0 {
+		return nil
+	}
+	return m
+}
+
+// mask returns the text with the secrets replaced, a nil masker leaves the text as is
+func (m *masker) mask(text string) string {
+	if m == nil {
+		return text
+	}
+
+	for _, v := range m.values {
+		text = strings.ReplaceAll(text, v, maskReplacement)
+	}
+
+	for _, d := range m.detectors {
+		text = d.mask(text)
+	}
+
+	return text
+}
+
+// maskingWriter masks the output line by line before writing it, so that the secrets split across writes
+// are masked all the same. Private key blocks spanning several lines are replaced as a whole
+type maskingWriter struct {
+	masker  *masker
+	out     io.Writer
+	lock    sync.Mutex
+	pending []byte
+	inPEM   bool
+}
+
+func (w *maskingWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexAny(w.pending, "\r\n")
+		if i < 0 {
+			break
+		}
+
+		err := w.writeLine(w.pending[:i+1])
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		w.pending = w.pending[i+1:]
+	}
+
+	if len(w.pending) > maskBufferLimit {
+		err := w.writeLine(w.pending)
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		w.pending = nil
+	}
+
+	return len(p), nil
+}
+
+// Flush masks and writes the unfinished line held back
+func (w *maskingWriter) Flush() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if len(w.pending) == 0 {
+		return nil
+	}
+
+	err := w.writeLine(w.pending)
+	w.pending = nil
+	return errors.WithStack(err)
+}
+
+func (w *maskingWriter) writeLine(line []byte) error {
+	text := string(line)
+	if w.inPEM {
+		if pemEndPattern.MatchString(text) {
+			w.inPEM = false
+		}
+		return nil
+	}
+
+	if !strings.Contains(text, "-----BEGIN") {
+		_, err := w.out.Write([]byte(w.masker.mask(text)))
+		return errors.WithStack(err)
+	}
+
+	if loc := pemBeginPattern.FindStringIndex(text); loc != nil && !pemEndPattern.MatchString(text[loc[1]:]) {
+		// the block goes on over the next lines, which are dropped until its end
+		w.inPEM = true
+		terminator := text[len(strings.TrimRight(text, "\r\n")):]
+		text = text[:loc[0]] + maskReplacement + terminator
+	}
+
+	_, err := w.out.Write([]byte(w.masker.mask(text)))
+	return errors.WithStack(err)
+}
+
+// maskedOutput returns a writer masking the output before writing it to the additional writers and the screen,
+// along with the function flushing the unfinished line it holds back
+func (r *Run) maskedOutput(screen io.Writer) (io.Writer, func() error) {
+	out := io.MultiWriter(append(r.additionalWriters, screen)...)
+	if r.masker == nil {
+		return out, func() error {
+			return nil
+		}
+	}
+
+	w := &maskingWriter{masker: r.masker, out: out}
+	return w, w.Flush
+}
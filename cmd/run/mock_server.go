@@ -0,0 +1,593 @@
+package run
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/alecthomas/kong"
+	"github.com/coder/quartz"
+	"github.com/google/go-github/v64/github"
+	"github.com/pkg/errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// mockAnnotationsLimit is the number of annotations GitHub accepts in a single request
+	mockAnnotationsLimit = 50
+	mockPerPageDefault   = 30
+	mockPerPageLimit     = 100
+	mockInspectPath      = "/_mock/check-runs"
+)
+
+var (
+	mockStatuses    = []string{checksStatusQueued, checksStatusInProgress, checksStatusCompleted}
+	mockConclusions = []string{"success", "failure", "neutral", "cancelled", "skipped", "timed_out", "action_required"}
+)
+
+// MockServer is the struct for the mock-server command, it serves the check runs endpoints of the GitHub API
+// from memory, to try out checks4shell without a GitHub App
+type MockServer struct {
+	Listen string `short:"L" env:"CHECKS4SHELL_LISTEN" help:"Address to listen on" default:":8080"`
+
+	stdout     io.Writer
+	stdoutLock *sync.Mutex
+	checks     *MockChecksService
+}
+
+// AfterApply will run on CLI and initialise the missing properties
+func (m *MockServer) AfterApply(_ *kong.Context) error {
+	m.init()
+	return nil
+}
+
+func (m *MockServer) init() {
+	if m.stdout == nil {
+		m.stdout = os.Stdout
+	}
+	m.stdoutLock = &sync.Mutex{}
+
+	if m.checks == nil {
+		m.checks = NewMockChecksService(quartz.NewReal())
+	}
+}
+
+// Run serves the mock API
+func (m *MockServer) Run() error {
+	return errors.WithStack(serveUntilSignal(m.Listen, m.handler(), m.stdout, &sync.WaitGroup{}))
+}
+
+func (m *MockServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /repos/{owner}/{repo}/check-runs", m.create)
+	mux.HandleFunc("PATCH /repos/{owner}/{repo}/check-runs/{id}", m.update)
+	mux.HandleFunc("GET /repos/{owner}/{repo}/check-runs/{id}", m.get)
+	mux.HandleFunc("GET /repos/{owner}/{repo}/commits/{ref}/check-runs", m.list)
+	mux.HandleFunc("GET "+mockInspectPath, m.inspect)
+	mux.HandleFunc("DELETE "+mockInspectPath, m.reset)
+	return m.logRequests(mux)
+}
+
+// logRequests prints out every request along with the status of its response
+func (m *MockServer) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, req)
+		m.checks.logRequest(req.Method, req.URL.RequestURI(), rec.status)
+		m.stdoutLock.Lock()
+		defer m.stdoutLock.Unlock()
+		_, _ = fmt.Fprintf(m.stdout, "%s %s %d\n", req.Method, req.URL.RequestURI(), rec.status)
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (m *MockServer) create(w http.ResponseWriter, req *http.Request) {
+	opts := github.CreateCheckRunOptions{}
+	if !decodeMockRequest(w, req, &opts) {
+		return
+	}
+
+	checkRun, _, err := m.checks.CreateCheckRun(req.Context(), req.PathValue("owner"), req.PathValue("repo"), opts)
+	writeMockResponse(w, http.StatusCreated, checkRun, err)
+}
+
+// mockUpdateRequest is the body of an update, along with the start time sent when a queued check run starts
+type mockUpdateRequest struct {
+	github.UpdateCheckRunOptions
+	StartedAt *github.Timestamp `json:"started_at,omitempty"`
+}
+
+func (m *MockServer) update(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseInt(req.PathValue("id"), 10, 64)
+	if err != nil {
+		writeMockError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	opts := mockUpdateRequest{}
+	if !decodeMockRequest(w, req, &opts) {
+		return
+	}
+
+	var checkRun *github.CheckRun
+	if opts.StartedAt != nil {
+		checkRun, _, err = m.checks.StartCheckRun(req.Context(), req.PathValue("owner"), req.PathValue("repo"), id, opts.StartedAt.Time, opts.UpdateCheckRunOptions)
+	} else {
+		checkRun, _, err = m.checks.UpdateCheckRun(req.Context(), req.PathValue("owner"), req.PathValue("repo"), id, opts.UpdateCheckRunOptions)
+	}
+	writeMockResponse(w, http.StatusOK, checkRun, err)
+}
+
+func (m *MockServer) get(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseInt(req.PathValue("id"), 10, 64)
+	if err != nil {
+		writeMockError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	checkRun, err := m.checks.get(req.PathValue("owner"), req.PathValue("repo"), id)
+	writeMockResponse(w, http.StatusOK, checkRun, err)
+}
+
+func (m *MockServer) list(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	opts := &github.ListCheckRunsOptions{
+		ListOptions: github.ListOptions{
+			Page:    atoiOr(query.Get("page"), 1),
+			PerPage: min(atoiOr(query.Get("per_page"), mockPerPageDefault), mockPerPageLimit),
+		},
+	}
+	if name := query.Get("check_name"); name != "" {
+		opts.CheckName = github.String(name)
+	}
+	if status := query.Get("status"); status != "" {
+		opts.Status = github.String(status)
+	}
+
+	result, resp, err := m.checks.ListCheckRunsForRef(req.Context(), req.PathValue("owner"), req.PathValue("repo"), req.PathValue("ref"), opts)
+	if err == nil && resp.NextPage != 0 {
+		next := *req.URL
+		query.Set("page", strconv.Itoa(resp.NextPage))
+		query.Set("per_page", strconv.Itoa(opts.PerPage))
+		next.RawQuery = query.Encode()
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.String()))
+	}
+	writeMockResponse(w, http.StatusOK, result, err)
+}
+
+// inspect returns every check run along with the requests received
+func (m *MockServer) inspect(w http.ResponseWriter, _ *http.Request) {
+	writeMockResponse(w, http.StatusOK, m.checks.snapshot(), nil)
+}
+
+// reset forgets every check run and request received
+func (m *MockServer) reset(w http.ResponseWriter, _ *http.Request) {
+	m.checks.reset()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func decodeMockRequest(w http.ResponseWriter, req *http.Request, v any) bool {
+	err := json.NewDecoder(req.Body).Decode(v)
+	if err != nil {
+		writeMockError(w, http.StatusBadRequest, fmt.Sprintf("Problems parsing JSON: %v", err))
+		return false
+	}
+	return true
+}
+
+func writeMockResponse(w http.ResponseWriter, status int, v any, err error) {
+	var respErr *github.ErrorResponse
+	if errors.As(err, &respErr) {
+		writeMockError(w, respErr.Response.StatusCode, respErr.Message)
+		return
+	}
+	if err != nil {
+		writeMockError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeMockError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+func atoiOr(s string, fallback int) int {
+	i, err := strconv.Atoi(s)
+	if err != nil || i <= 0 {
+		return fallback
+	}
+	return i
+}
+
+// mockRequest is a request received by the mock server
+type mockRequest struct {
+	Time   time.Time `json:"time"`
+	Method string    `json:"method"`
+	Path   string    `json:"path"`
+	Status int       `json:"status"`
+}
+
+// mockCheckRun is a check run kept by the mock checks service, along with the repository it belongs to
+type mockCheckRun struct {
+	Owner      string           `json:"owner"`
+	Repository string           `json:"repository"`
+	CheckRun   *github.CheckRun `json:"check_run"`
+}
+
+// mockSnapshot is what the inspection endpoint returns
+type mockSnapshot struct {
+	CheckRuns []*mockCheckRun `json:"check_runs"`
+	Requests  []*mockRequest  `json:"requests"`
+}
+
+// MockChecksService is a checks service keeping the check runs in memory, enforcing the limits of GitHub.
+// It backs the mock-server command, and can stand in for GitHub in the tests of programs embedding checks4shell
+type MockChecksService struct {
+	clock     quartz.Clock
+	lock      *sync.Mutex
+	lastID    int64
+	checkRuns []*mockCheckRun
+	requests  []*mockRequest
+	calls     []*MockCall
+
+	// Fail returns the error the given method should fail with, if any, e.g. to act as an unreachable GitHub
+	Fail func(method string) error
+}
+
+// MockCall is a call accepted by the mock checks service, along with the options it was given
+type MockCall struct {
+	Owner      string
+	Repository string
+	CheckRunID int64
+	// Options are the github.CreateCheckRunOptions or the github.UpdateCheckRunOptions of the call
+	Options   any
+	StartedAt *time.Time
+}
+
+// NewMockChecksService returns an empty mock checks service, timestamping the check runs with the clock
+func NewMockChecksService(clock quartz.Clock) *MockChecksService {
+	return &MockChecksService{
+		clock: clock,
+		lock:  &sync.Mutex{},
+	}
+}
+
+// CreateCheckRun creates a check run
+func (s *MockChecksService) CreateCheckRun(_ context.Context, owner, repo string, opts github.CreateCheckRunOptions) (*github.CheckRun, *github.Response, error) {
+	err := s.fail("CreateCheckRun")
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	if opts.Name == "" || opts.HeadSHA == "" {
+		return nil, nil, mockError(http.StatusUnprocessableEntity, "Invalid request.\n\nname and head_sha are required.")
+	}
+
+	err = validateMockCheckRun(opts.Status, opts.Conclusion, opts.Output, opts.Actions)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.lastID++
+	checkRun := &github.CheckRun{
+		ID:          github.Int64(s.lastID),
+		Name:        github.String(opts.Name),
+		HeadSHA:     github.String(opts.HeadSHA),
+		DetailsURL:  opts.DetailsURL,
+		ExternalID:  opts.ExternalID,
+		Status:      github.String(checksStatusQueued),
+		Conclusion:  opts.Conclusion,
+		StartedAt:   opts.StartedAt,
+		CompletedAt: opts.CompletedAt,
+		Output:      &github.CheckRunOutput{AnnotationsCount: github.Int(0)},
+	}
+	if opts.Status != nil {
+		checkRun.Status = opts.Status
+	}
+	if checkRun.StartedAt == nil && checkRun.GetStatus() != checksStatusQueued {
+		checkRun.StartedAt = &github.Timestamp{Time: s.clock.Now()}
+	}
+	applyMockOutput(checkRun, opts.Output)
+	completeMockCheckRun(checkRun, s.clock.Now())
+
+	s.checkRuns = append(s.checkRuns, &mockCheckRun{Owner: owner, Repository: repo, CheckRun: checkRun})
+	s.calls = append(s.calls, &MockCall{Owner: owner, Repository: repo, CheckRunID: s.lastID, Options: opts})
+	return copyCheckRun(checkRun), &github.Response{}, nil
+}
+
+// UpdateCheckRun updates the check run of the given ID
+func (s *MockChecksService) UpdateCheckRun(_ context.Context, owner, repo string, checkRunID int64, opts github.UpdateCheckRunOptions) (*github.CheckRun, *github.Response, error) {
+	return s.update("UpdateCheckRun", owner, repo, checkRunID, time.Time{}, opts)
+}
+
+// StartCheckRun updates the check run of the given ID, setting its start time
+func (s *MockChecksService) StartCheckRun(_ context.Context, owner, repo string, checkRunID int64, startedAt time.Time, opts github.UpdateCheckRunOptions) (*github.CheckRun, *github.Response, error) {
+	return s.update("StartCheckRun", owner, repo, checkRunID, startedAt, opts)
+}
+
+// update updates the check run of the given ID, setting its start time if given
+func (s *MockChecksService) update(method string, owner, repo string, checkRunID int64, startedAt time.Time, opts github.UpdateCheckRunOptions) (*github.CheckRun, *github.Response, error) {
+	err := s.fail(method)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	err = validateMockCheckRun(opts.Status, opts.Conclusion, opts.Output, opts.Actions)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	checkRun := s.find(owner, repo, checkRunID)
+	if checkRun == nil {
+		return nil, nil, mockError(http.StatusNotFound, "Not Found")
+	}
+
+	if opts.Name != "" {
+		checkRun.Name = github.String(opts.Name)
+	}
+	if opts.DetailsURL != nil {
+		checkRun.DetailsURL = opts.DetailsURL
+	}
+	if opts.ExternalID != nil {
+		checkRun.ExternalID = opts.ExternalID
+	}
+	if opts.Status != nil {
+		checkRun.Status = opts.Status
+	}
+	if opts.Conclusion != nil {
+		checkRun.Conclusion = opts.Conclusion
+	}
+	if opts.CompletedAt != nil {
+		checkRun.CompletedAt = opts.CompletedAt
+	}
+	call := &MockCall{Owner: owner, Repository: repo, CheckRunID: checkRunID, Options: opts}
+	if !startedAt.IsZero() {
+		checkRun.StartedAt = &github.Timestamp{Time: startedAt}
+		call.StartedAt = &startedAt
+	}
+	applyMockOutput(checkRun, opts.Output)
+	completeMockCheckRun(checkRun, s.clock.Now())
+	s.calls = append(s.calls, call)
+
+	return copyCheckRun(checkRun), &github.Response{}, nil
+}
+
+// ListCheckRunsForRef lists the check runs of the given commit
+func (s *MockChecksService) ListCheckRunsForRef(_ context.Context, owner, repo, ref string, opts *github.ListCheckRunsOptions) (*github.ListCheckRunsResults, *github.Response, error) {
+	err := s.fail("ListCheckRunsForRef")
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	if opts == nil {
+		opts = &github.ListCheckRunsOptions{}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	matching := make([]*github.CheckRun, 0)
+	for _, c := range s.checkRuns {
+		if c.Owner != owner || c.Repository != repo || c.CheckRun.GetHeadSHA() != ref {
+			continue
+		}
+		if opts.CheckName != nil && c.CheckRun.GetName() != opts.GetCheckName() {
+			continue
+		}
+		if opts.Status != nil && c.CheckRun.GetStatus() != opts.GetStatus() {
+			continue
+		}
+		matching = append(matching, copyCheckRun(c.CheckRun))
+	}
+
+	page := max(opts.Page, 1)
+	perPage := opts.PerPage
+	if perPage <= 0 {
+		perPage = mockPerPageDefault
+	}
+	start := min((page-1)*perPage, len(matching))
+	end := min(start+perPage, len(matching))
+
+	resp := &github.Response{}
+	if end < len(matching) {
+		resp.NextPage = page + 1
+	}
+
+	return &github.ListCheckRunsResults{
+		Total:     github.Int(len(matching)),
+		CheckRuns: matching[start:end],
+	}, resp, nil
+}
+
+// CheckRuns returns a copy of every check run, in the order they were created
+func (s *MockChecksService) CheckRuns() []*github.CheckRun {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	checkRuns := make([]*github.CheckRun, 0, len(s.checkRuns))
+	for _, c := range s.checkRuns {
+		checkRuns = append(checkRuns, copyCheckRun(c.CheckRun))
+	}
+	return checkRuns
+}
+
+// Calls returns a copy of every call accepted, in the order they were received
+func (s *MockChecksService) Calls() []MockCall {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	calls := make([]MockCall, 0, len(s.calls))
+	for _, c := range s.calls {
+		calls = append(calls, *c)
+	}
+	return calls
+}
+
+// fail returns the error the method should fail with, if any. The callback is called without the lock held so
+// that it can inspect the service
+func (s *MockChecksService) fail(method string) error {
+	s.lock.Lock()
+	fail := s.Fail
+	s.lock.Unlock()
+	if fail == nil {
+		return nil
+	}
+	return fail(method)
+}
+
+func (s *MockChecksService) get(owner, repo string, checkRunID int64) (*github.CheckRun, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	checkRun := s.find(owner, repo, checkRunID)
+	if checkRun == nil {
+		return nil, mockError(http.StatusNotFound, "Not Found")
+	}
+	return copyCheckRun(checkRun), nil
+}
+
+// find returns the check run of the given ID, the lock must be held
+func (s *MockChecksService) find(owner, repo string, checkRunID int64) *github.CheckRun {
+	for _, c := range s.checkRuns {
+		if c.Owner == owner && c.Repository == repo && c.CheckRun.GetID() == checkRunID {
+			return c.CheckRun
+		}
+	}
+	return nil
+}
+
+func (s *MockChecksService) logRequest(method string, path string, status int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.requests = append(s.requests, &mockRequest{
+		Time:   s.clock.Now(),
+		Method: method,
+		Path:   path,
+		Status: status,
+	})
+}
+
+func (s *MockChecksService) snapshot() *mockSnapshot {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	snapshot := &mockSnapshot{
+		CheckRuns: make([]*mockCheckRun, 0, len(s.checkRuns)),
+		Requests:  append(make([]*mockRequest, 0, len(s.requests)), s.requests...),
+	}
+	for _, c := range s.checkRuns {
+		snapshot.CheckRuns = append(snapshot.CheckRuns, &mockCheckRun{
+			Owner:      c.Owner,
+			Repository: c.Repository,
+			CheckRun:   copyCheckRun(c.CheckRun),
+		})
+	}
+	return snapshot
+}
+
+func (s *MockChecksService) reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.checkRuns = nil
+	s.requests = nil
+	s.calls = nil
+}
+
+// validateMockCheckRun enforces the limits GitHub puts on the check runs
+func validateMockCheckRun(status *string, conclusion *string, output *github.CheckRunOutput, actions []*github.CheckRunAction) error {
+	if status != nil && !slices.Contains(mockStatuses, *status) {
+		return mockError(http.StatusUnprocessableEntity, fmt.Sprintf("Invalid request.\n\n%q is not a valid status.", *status))
+	}
+	if conclusion != nil && !slices.Contains(mockConclusions, *conclusion) {
+		return mockError(http.StatusUnprocessableEntity, fmt.Sprintf("Invalid request.\n\n%q is not a valid conclusion.", *conclusion))
+	}
+
+	if output != nil {
+		for field, value := range map[string]string{"summary": output.GetSummary(), "text": output.GetText()} {
+			if n := utf8.RuneCountInString(value); n > summaryLimit {
+				return mockError(http.StatusUnprocessableEntity, fmt.Sprintf("Invalid request.\n\nOnly %d characters are allowed in output.%s; %d were supplied.", summaryLimit, field, n))
+			}
+		}
+		if len(output.Annotations) > mockAnnotationsLimit {
+			return mockError(http.StatusUnprocessableEntity, fmt.Sprintf("Invalid request.\n\nOnly %d annotations are allowed per request; %d were supplied.", mockAnnotationsLimit, len(output.Annotations)))
+		}
+	}
+
+	if len(actions) > actionsLimit {
+		return mockError(http.StatusUnprocessableEntity, fmt.Sprintf("Invalid request.\n\nOnly %d actions are allowed; %d were supplied.", actionsLimit, len(actions)))
+	}
+	for _, a := range actions {
+		err := validateAction(a)
+		if err != nil {
+			return mockError(http.StatusUnprocessableEntity, fmt.Sprintf("Invalid request.\n\n%v", err))
+		}
+	}
+
+	return nil
+}
+
+// applyMockOutput replaces the output of the check run, appending the annotations to the ones sent before
+func applyMockOutput(checkRun *github.CheckRun, output *github.CheckRunOutput) {
+	if output == nil {
+		return
+	}
+
+	annotations := append(checkRun.GetOutput().Annotations, output.Annotations...)
+	checkRun.Output = &github.CheckRunOutput{
+		Title:            output.Title,
+		Summary:          output.Summary,
+		Text:             output.Text,
+		Annotations:      annotations,
+		AnnotationsCount: github.Int(len(annotations)),
+		Images:           output.Images,
+	}
+}
+
+// completeMockCheckRun marks the check run completed as soon as it has a conclusion, as GitHub does
+func completeMockCheckRun(checkRun *github.CheckRun, now time.Time) {
+	if checkRun.Conclusion == nil {
+		return
+	}
+
+	checkRun.Status = github.String(checksStatusCompleted)
+	if checkRun.CompletedAt == nil {
+		checkRun.CompletedAt = &github.Timestamp{Time: now}
+	}
+}
+
+func copyCheckRun(checkRun *github.CheckRun) *github.CheckRun {
+	c := *checkRun
+	if checkRun.Output != nil {
+		output := *checkRun.Output
+		c.Output = &output
+	}
+	return &c
+}
+
+func mockError(status int, message string) error {
+	return &github.ErrorResponse{
+		Response: &http.Response{StatusCode: status, Request: &http.Request{URL: &url.URL{}}},
+		Message:  message,
+	}
+}
@@ -0,0 +1,176 @@
+package run
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/coder/quartz"
+	"github.com/google/go-github/v64/github"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newMockServer serves a mock server and returns a checks service sending its requests to it
+func newMockServer(t *testing.T) (*MockServer, *httptest.Server, ChecksService) {
+	t.Helper()
+	clock := quartz.NewMock(t)
+	clock.Set(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	m := &MockServer{stdout: &bytes.Buffer{}, checks: NewMockChecksService(clock)}
+	m.init()
+	server := httptest.NewServer(m.handler())
+	t.Cleanup(server.Close)
+
+	client := github.NewClient(nil)
+	baseURL, err := url.Parse(server.URL + "/")
+	require.NoError(t, err)
+	client.BaseURL = baseURL
+	return m, server, NewChecksService(client)
+}
+
+func inspectMockServer(t *testing.T, server *httptest.Server) *mockSnapshot {
+	t.Helper()
+	resp, err := http.Get(server.URL + mockInspectPath)
+	require.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	snapshot := &mockSnapshot{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(snapshot))
+	return snapshot
+}
+
+func TestMockServerRun(t *testing.T) {
+	t.Parallel()
+	m, server, service := newMockServer(t)
+	r, _ := newRun(t, &runConfig{frequency: 5 * time.Second}, command(t, "echo", "mocked")...)
+	r.checksService = service
+	r.CreateQueued = true
+
+	require.NoError(t, r.Run(&Config{}))
+
+	snapshot := inspectMockServer(t, server)
+	require.Len(t, snapshot.CheckRuns, 1)
+	c := snapshot.CheckRuns[0]
+	require.Equal(t, sampleOwner, c.Owner)
+	require.Equal(t, sampleRepo, c.Repository)
+	require.Equal(t, int64(1), c.CheckRun.GetID())
+	require.Equal(t, sampleName, c.CheckRun.GetName())
+	require.Equal(t, checksStatusCompleted, c.CheckRun.GetStatus())
+	require.Equal(t, checksConclusionSuccess, c.CheckRun.GetConclusion())
+	require.True(t, r.startedAt.Equal(c.CheckRun.GetStartedAt().Time))
+	require.Contains(t, c.CheckRun.GetOutput().GetText(), "mocked")
+
+	paths := make([]string, 0)
+	for _, req := range snapshot.Requests {
+		paths = append(paths, req.Method+" "+req.Path)
+	}
+	require.Equal(t, []string{
+		"POST /repos/sampleOwner/sampleRepo/check-runs",
+		"PATCH /repos/sampleOwner/sampleRepo/check-runs/1",
+		"PATCH /repos/sampleOwner/sampleRepo/check-runs/1",
+	}, paths)
+	require.Contains(t, m.stdout.(*bytes.Buffer).String(), "POST /repos/sampleOwner/sampleRepo/check-runs 201\n")
+}
+
+func TestMockServerLimits(t *testing.T) {
+	t.Parallel()
+	_, _, service := newMockServer(t)
+	ctx := context.Background()
+
+	_, _, err := service.CreateCheckRun(ctx, sampleOwner, sampleRepo, github.CreateCheckRunOptions{
+		Name:    sampleName,
+		HeadSHA: sampleHeadShA,
+		Output: &github.CheckRunOutput{
+			Title:   github.String(sampleTitle),
+			Summary: github.String(strings.Repeat("a", summaryLimit+1)),
+		},
+	})
+	require.ErrorContains(t, err, "422 Invalid request.\n\nOnly 65535 characters are allowed in output.summary; 65536 were supplied.")
+
+	annotations := make([]*github.CheckRunAnnotation, mockAnnotationsLimit+1)
+	_, _, err = service.CreateCheckRun(ctx, sampleOwner, sampleRepo, github.CreateCheckRunOptions{
+		Name:    sampleName,
+		HeadSHA: sampleHeadShA,
+		Output:  &github.CheckRunOutput{Title: github.String(sampleTitle), Summary: github.String(sampleSummary), Annotations: annotations},
+	})
+	require.ErrorContains(t, err, "Only 50 annotations are allowed per request; 51 were supplied.")
+
+	_, _, err = service.UpdateCheckRun(ctx, sampleOwner, sampleRepo, 42, github.UpdateCheckRunOptions{Name: sampleName})
+	require.ErrorContains(t, err, "404 Not Found")
+}
+
+func TestMockServerAnnotationsAccumulate(t *testing.T) {
+	t.Parallel()
+	_, server, service := newMockServer(t)
+	ctx := context.Background()
+	annotation := &github.CheckRunAnnotation{Path: github.String("main.go"), Message: github.String("message")}
+
+	checkRun, _, err := service.CreateCheckRun(ctx, sampleOwner, sampleRepo, github.CreateCheckRunOptions{
+		Name:    sampleName,
+		HeadSHA: sampleHeadShA,
+		Output:  &github.CheckRunOutput{Title: github.String(sampleTitle), Summary: github.String(sampleSummary), Annotations: []*github.CheckRunAnnotation{annotation}},
+	})
+	require.NoError(t, err)
+	_, _, err = service.UpdateCheckRun(ctx, sampleOwner, sampleRepo, checkRun.GetID(), github.UpdateCheckRunOptions{
+		Name:   sampleName,
+		Output: &github.CheckRunOutput{Title: github.String(sampleTitle), Summary: github.String(sampleSummary), Annotations: []*github.CheckRunAnnotation{annotation}},
+	})
+	require.NoError(t, err)
+
+	snapshot := inspectMockServer(t, server)
+	require.Equal(t, 2, snapshot.CheckRuns[0].CheckRun.GetOutput().GetAnnotationsCount())
+}
+
+func TestMockServerListPaginates(t *testing.T) {
+	t.Parallel()
+	_, server, service := newMockServer(t)
+	ctx := context.Background()
+	for i := 0; i < mockPerPageDefault+2; i++ {
+		_, _, err := service.CreateCheckRun(ctx, sampleOwner, sampleRepo, github.CreateCheckRunOptions{Name: sampleName, HeadSHA: sampleHeadShA})
+		require.NoError(t, err)
+	}
+	_, _, err := service.CreateCheckRun(ctx, sampleOwner, sampleRepo, github.CreateCheckRunOptions{Name: "other", HeadSHA: sampleHeadShA})
+	require.NoError(t, err)
+
+	r, _ := newRun(t, &runConfig{}, command(t, "echo", "attached")...)
+	r.checksService = service
+	r.ReuseByExternalID = true
+	_, _, err = service.CreateCheckRun(ctx, sampleOwner, sampleRepo, github.CreateCheckRunOptions{Name: sampleName, HeadSHA: sampleHeadShA, ExternalID: github.String(sampleExternalID)})
+	require.NoError(t, err)
+
+	require.NoError(t, r.attachCheckRun())
+	require.Equal(t, int64(mockPerPageDefault+4), r.runId)
+
+	req, err := http.NewRequest(http.MethodDelete, server.URL+mockInspectPath, nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Empty(t, inspectMockServer(t, server).CheckRuns)
+}
+
+func TestMockChecksServiceFailInspectsService(t *testing.T) {
+	t.Parallel()
+	s := NewMockChecksService(quartz.NewMock(t))
+	s.Fail = func(method string) error {
+		if len(s.CheckRuns()) > 0 {
+			return mockError(http.StatusServiceUnavailable, "Unavailable")
+		}
+		return nil
+	}
+
+	ctx := context.Background()
+	opts := github.CreateCheckRunOptions{Name: sampleName, HeadSHA: sampleHeadShA}
+	_, _, err := s.CreateCheckRun(ctx, sampleOwner, sampleRepo, opts)
+	require.NoError(t, err)
+	_, _, err = s.CreateCheckRun(ctx, sampleOwner, sampleRepo, opts)
+	require.ErrorContains(t, err, "Unavailable")
+	require.Len(t, s.Calls(), 1)
+}
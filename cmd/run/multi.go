@@ -0,0 +1,277 @@
+package run
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/alecthomas/kong"
+	"github.com/coder/quartz"
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Multi is the struct for the multi command, it runs the checks listed in a manifest concurrently
+type Multi struct {
+	Owner           string        `short:"o" env:"CHECKS4SHELL_OWNER" required:"" help:"The owner of the target GitHub repo"`
+	Repository      string        `short:"r" env:"CHECKS4SHELL_REPOSITORY" required:"" help:"The target GitHub repository"`
+	CommitSHA       string        `short:"c" env:"CHECKS4SHELL_COMMIT_SHA" required:"" help:"The target SHA of the check Runs to be created"`
+	UpdateFrequency time.Duration `short:"f" env:"CHECKS4SHELL_UPDATE_FREQUENCY" help:"Frequency to update the check runs" default:"5s"`
+	Concurrency     int           `short:"p" env:"CHECKS4SHELL_CONCURRENCY" help:"Maximum number of commands running at the same time" default:"4"`
+	Debug           bool          `short:"d" help:"Enable debug mode"`
+	Manifest        string        `arg:"" type:"existingfile" help:"JSON manifest file listing the checks to run, each with a name, title and command"`
+
+	clock quartz.Clock
+
+	stdout          io.Writer
+	stdoutLock      *sync.Mutex
+	checksService   ChecksService
+	isAuthenticated bool
+	sigChan         chan os.Signal
+	env             []string
+}
+
+// MultiCheck is a single entry of the multi command manifest
+type MultiCheck struct {
+	Name            string   `json:"name"`
+	Title           string   `json:"title"`
+	Summary         string   `json:"summary"`
+	DetailsURL      string   `json:"details_url"`
+	ExternalID      string   `json:"external_id"`
+	SyntaxHighlight string   `json:"syntax_highlight"`
+	Command         []string `json:"command"`
+}
+
+// AfterApply will run on CLI and initialise the missing properties
+func (m *Multi) AfterApply(_ *kong.Context, cfg *Config) error {
+	if m.clock == nil {
+		m.clock = quartz.NewReal()
+	}
+
+	if m.stdout == nil {
+		m.stdout = os.Stdout
+	}
+
+	m.checksService = cfg.ChecksService
+	m.isAuthenticated = cfg.IsAuthenticated
+
+	m.sigChan = make(chan os.Signal, 1)
+	signal.Notify(m.sigChan, defaultSignalPolicy.signals()...)
+
+	return nil
+}
+
+// Run runs all the commands in the manifest
+func (m *Multi) Run(cfg *Config) error {
+	checks, err := readManifest(m.Manifest)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return errors.WithStack(m.run(checks))
+}
+
+func readManifest(path string) ([]*MultiCheck, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading manifest")
+	}
+
+	var checks []*MultiCheck
+	err = json.Unmarshal(content, &checks)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing manifest")
+	}
+
+	names := map[string]bool{}
+	for i, c := range checks {
+		if c.Name == "" {
+			return nil, errors.Errorf("check %d in manifest has no name", i)
+		}
+		if len(c.Command) == 0 {
+			return nil, errors.Errorf("check %s in manifest has no command", c.Name)
+		}
+		if names[c.Name] {
+			return nil, errors.Errorf("check %s is defined more than once in manifest", c.Name)
+		}
+		names[c.Name] = true
+	}
+
+	return checks, nil
+}
+
+func (m *Multi) newRun(check *MultiCheck, stdoutLock *sync.Mutex) (*Run, error) {
+	title := check.Title
+	if title == "" {
+		title = check.Name
+	}
+
+	r := &Run{
+		Owner:           m.Owner,
+		Repository:      m.Repository,
+		CommitSHA:       m.CommitSHA,
+		Name:            check.Name,
+		Title:           title,
+		DetailsURL:      check.DetailsURL,
+		ExternalID:      check.ExternalID,
+		Summary:         check.Summary,
+		UpdateFrequency: m.UpdateFrequency,
+		SyntaxHighlight: check.SyntaxHighlight,
+		Debug:           m.Debug,
+		ShellCommand:    check.Command,
+	}
+
+	err := r.Init(Options{
+		Clock: m.clock,
+		Writers: []io.Writer{
+			&prefixWriter{prefix: fmt.Sprintf("[%s] ", check.Name), out: m.stdout, lock: stdoutLock, lineStart: true},
+		},
+		ChecksService: m.checksService,
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	r.isAuthenticated = m.isAuthenticated
+	r.env = m.env
+
+	err = r.prepare()
+	if err != nil {
+		return nil, errors.Wrapf(err, "check %s", check.Name)
+	}
+
+	return r, nil
+}
+
+func (m *Multi) run(checks []*MultiCheck) error {
+	stdoutLock := m.stdoutLock
+	if stdoutLock == nil {
+		stdoutLock = &sync.Mutex{}
+	}
+	runs := make([]*Run, len(checks))
+	for i, check := range checks {
+		r, err := m.newRun(check, stdoutLock)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		runs[i] = r
+	}
+
+	// forward the signals received to every child
+	stopForwarding := make(chan struct{})
+	defer close(stopForwarding)
+	go func() {
+		for {
+			select {
+			case s := <-m.sigChan:
+				for _, r := range runs {
+					select {
+					case r.sigChan <- s:
+					default:
+					}
+				}
+			case <-stopForwarding:
+				return
+			}
+		}
+	}()
+
+	concurrency := m.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	semaphore := make(chan struct{}, concurrency)
+
+	wg := &sync.WaitGroup{}
+	errs := make([]error, len(runs))
+	for i, r := range runs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			errs[i] = r.run()
+		}()
+	}
+	wg.Wait()
+
+	failures := &multiError{errs: map[string]error{}}
+	for i, err := range errs {
+		if err != nil {
+			failures.errs[runs[i].Name] = err
+		}
+	}
+
+	if len(failures.errs) > 0 {
+		return failures
+	}
+
+	return nil
+}
+
+// multiError collects the errors of failed checks, its exit code is the worst exit code of them
+type multiError struct {
+	errs map[string]error
+}
+
+func (e *multiError) Error() string {
+	names := make([]string, 0, len(e.errs))
+	for name := range e.errs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	msgs := make([]string, len(names))
+	for i, name := range names {
+		msgs[i] = fmt.Sprintf("%s: %v", name, e.errs[name])
+	}
+	return fmt.Sprintf("%d check(s) failed: %s", len(names), strings.Join(msgs, "; "))
+}
+
+// ExitCode returns the highest exit code of the failed commands
+func (e *multiError) ExitCode() int {
+	code := 0
+	for _, err := range e.errs {
+		c := 1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+			c = exitErr.ExitCode()
+		}
+		if c > code {
+			code = c
+		}
+	}
+	return code
+}
+
+// prefixWriter prefixes each line written with the given prefix, writes are serialised by the shared lock
+type prefixWriter struct {
+	prefix    string
+	out       io.Writer
+	lock      *sync.Mutex
+	lineStart bool
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	buf := make([]byte, 0, len(p)+len(w.prefix))
+	for _, b := range p {
+		if w.lineStart {
+			buf = append(buf, w.prefix...)
+		}
+		buf = append(buf, b)
+		w.lineStart = b == '\n'
+	}
+
+	_, err := w.out.Write(buf)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,126 @@
+package run
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/alecthomas/kong"
+	"github.com/coder/quartz"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newMulti(t *testing.T, stdout *bytes.Buffer) *Multi {
+	t.Helper()
+	clock := quartz.NewMock(t)
+	clock.Set(time.Now())
+	return &Multi{
+		Owner:           sampleOwner,
+		Repository:      sampleRepo,
+		CommitSHA:       sampleHeadShA,
+		UpdateFrequency: 5 * time.Second,
+		Concurrency:     2,
+		clock:           clock,
+		stdout:          stdout,
+		checksService:   NewMockChecksService(clock),
+		isAuthenticated: true,
+		sigChan:         make(chan os.Signal, 1),
+	}
+}
+
+func writeManifest(t *testing.T, checks []*MultiCheck) string {
+	t.Helper()
+	content, err := json.Marshal(checks)
+	require.NoError(t, err)
+	f := filepath.Join(t.TempDir(), "manifest.json")
+	require.NoError(t, os.WriteFile(f, content, 0644))
+	return f
+}
+
+// lastConclusions returns the conclusion of each check run by name
+func lastConclusions(t *testing.T, s *MockChecksService) map[string]string {
+	t.Helper()
+	out := map[string]string{}
+	for _, c := range s.CheckRuns() {
+		out[c.GetName()] = c.GetConclusion()
+	}
+	return out
+}
+
+func TestMultiRunsAllChecks(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	m := newMulti(t, stdout)
+	m.Manifest = writeManifest(t, []*MultiCheck{
+		{Name: "lint", Command: command(t, "echo", "linted")},
+		{Name: "test", Command: command(t, "errorm", "3", "test failed")},
+		{Name: "build", Command: command(t, "errorm", "2", "build failed")},
+	})
+
+	err := m.Run(&Config{})
+	require.Error(t, err)
+	var exitCoder kong.ExitCoder
+	require.True(t, errors.As(err, &exitCoder))
+	require.Equal(t, 3, exitCoder.ExitCode())
+	require.Contains(t, err.Error(), "2 check(s) failed")
+
+	require.Equal(t, map[string]string{
+		"lint":  checksConclusionSuccess,
+		"test":  checksConclusionFailure,
+		"build": checksConclusionFailure,
+	}, lastConclusions(t, m.checksService.(*MockChecksService)))
+	require.Contains(t, stdout.String(), "[lint] linted\n")
+}
+
+func TestMultiAllSucceed(t *testing.T) {
+	m := newMulti(t, &bytes.Buffer{})
+	m.Manifest = writeManifest(t, []*MultiCheck{
+		{Name: "one", Command: command(t, "echo", "1")},
+		{Name: "two", Command: command(t, "echo", "2")},
+	})
+
+	require.NoError(t, m.Run(&Config{}))
+}
+
+func TestMultiChecksAreInitialised(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	m := newMulti(t, stdout)
+	m.Manifest = writeManifest(t, []*MultiCheck{
+		{Name: "leak", Command: command(t, "echo", "token "+sampleGithubToken)},
+	})
+
+	require.NoError(t, m.Run(&Config{}))
+	require.NotContains(t, stdout.String(), sampleGithubToken)
+	require.Contains(t, stdout.String(), "[leak] token ***\n")
+
+	r, err := m.newRun(&MultiCheck{Name: "lint", Command: command(t, "echo")}, &sync.Mutex{})
+	require.NoError(t, err)
+	require.Equal(t, 5*time.Second, r.KillGracePeriod)
+	require.Equal(t, 10000, r.Scrollback)
+	require.NotNil(t, r.signalPolicy)
+}
+
+func TestMultiManifestValidation(t *testing.T) {
+	_, err := readManifest(writeManifest(t, []*MultiCheck{{Name: "no-command"}}))
+	require.ErrorContains(t, err, "has no command")
+
+	_, err = readManifest(writeManifest(t, []*MultiCheck{
+		{Name: "dup", Command: []string{"true"}},
+		{Name: "dup", Command: []string{"true"}},
+	}))
+	require.ErrorContains(t, err, "more than once")
+}
+
+func TestPrefixWriter(t *testing.T) {
+	t.Parallel()
+	out := &bytes.Buffer{}
+	w := &prefixWriter{prefix: "[a] ", out: out, lock: &sync.Mutex{}, lineStart: true}
+	_, err := w.Write([]byte("line 1\nline"))
+	require.NoError(t, err)
+	_, err = w.Write([]byte(" 2\n"))
+	require.NoError(t, err)
+	require.Equal(t, "[a] line 1\n[a] line 2\n", out.String())
+}
@@ -0,0 +1,130 @@
+package run
+
+import (
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// outputDrainTimeout is how long the output left in the pipe or the pseudo-terminal is read once the command
+	// finished, processes which left the group of the command and still hold it open would otherwise keep the run going
+	outputDrainTimeout = time.Second
+	// groupPollInterval is how often the group of the command is checked for leftover processes during the grace period
+	groupPollInterval = 50 * time.Millisecond
+
+	leftoversTerminated = "terminated"
+	leftoversKilled     = "killed"
+)
+
+// newCommand returns the command to run with the environment of the run added to the inherited one
+func (r *Run) newCommand(args []string) *exec.Cmd {
+	cmd := exec.Command(args[0], args[1:]...)
+	if len(r.env) > 0 {
+		cmd.Env = append(os.Environ(), r.env...)
+	}
+	return cmd
+}
+
+// startCommand starts the command in its own process group with its output written to out, through a
+// pseudo-terminal with --pty, to be killed once flooded is closed. It returns the function waiting for the command
+// to finish, for its leftover processes to be cleaned up and for its output to be written
+func (r *Run) startCommand(cmd *exec.Cmd, out io.Writer, flooded <-chan struct{}) (func() error, error) {
+	var reader, writer *os.File
+	var err error
+	if r.Pty {
+		reader, writer, err = openPty(r.ptyCols, r.ptyRows)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		cmd.Stdin = writer
+		// the command leads a new session, and so a new process group
+		cmd.SysProcAttr = ptyAttr()
+	} else {
+		// the output goes through a pipe rather than a writer so that waiting for the command does not wait for
+		// the processes it left behind to close their output
+		reader, writer, err = os.Pipe()
+		if err != nil {
+			return nil, errors.Wrap(err, "error creating output pipe")
+		}
+		cmd.SysProcAttr = groupAttr()
+	}
+
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+	err = cmd.Start()
+	// the command has its own copy of the writing end
+	_ = writer.Close()
+	if err != nil {
+		_ = reader.Close()
+		return nil, errors.WithStack(err)
+	}
+
+	if r.Pty {
+		// without input the end of file is still typed, the command would wait for it on the pseudo-terminal otherwise
+		input := r.ptyInput
+		if input == nil {
+			input = strings.NewReader("")
+		}
+		go typeInput(reader, input)
+	}
+
+	copied := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(out, reader)
+		// reading the master end of a pseudo-terminal fails with EIO once every process closed the slave end
+		if errors.Is(err, syscall.EIO) || errors.Is(err, os.ErrDeadlineExceeded) {
+			err = nil
+		}
+		copied <- err
+	}()
+
+	return func() error {
+		err := r.wait(cmd, flooded)
+		r.recordUsage(cmd.ProcessState)
+		r.cleanupGroup(cmd.Process.Pid)
+		_ = reader.SetReadDeadline(time.Now().Add(outputDrainTimeout))
+		copyErr := <-copied
+		_ = reader.Close()
+		if err == nil && copyErr != nil {
+			err = errors.Wrap(copyErr, "error reading the output of the command")
+		}
+		return err
+	}, nil
+}
+
+// cleanupGroup terminates the processes left in the group of the finished command, kills them when they are still
+// there after the grace period, and records the cleanup for the summary
+func (r *Run) cleanupGroup(pid int) {
+	if !groupAlive(pid) {
+		return
+	}
+
+	cleanup := leftoversTerminated
+	_ = signalGroup(pid, syscall.SIGTERM)
+	grace := r.clock.NewTimer(r.KillGracePeriod, "cleanup-group", "grace")
+	defer grace.Stop()
+	poll := r.clock.NewTicker(groupPollInterval, "cleanup-group", "poll")
+	defer poll.Stop()
+wait:
+	for groupAlive(pid) {
+		select {
+		case <-grace.C:
+			_ = signalGroup(pid, syscall.SIGKILL)
+			cleanup = leftoversKilled
+			break wait
+		case <-poll.C:
+		}
+	}
+
+	r.resultLock.Lock()
+	defer r.resultLock.Unlock()
+	// a step killing its leftovers is not hidden by a later one only terminating them
+	if r.leftovers != leftoversKilled {
+		r.leftovers = cleanup
+	}
+}
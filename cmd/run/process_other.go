@@ -0,0 +1,37 @@
+//go:build !unix
+
+package run
+
+import (
+	"github.com/pkg/errors"
+	"os"
+	"syscall"
+)
+
+func groupAttr() *syscall.SysProcAttr {
+	return nil
+}
+
+// signalGroup only signals the process of the given ID, there are no process groups on this platform
+func signalGroup(pid int, s os.Signal) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(p.Signal(s))
+}
+
+func groupAlive(int) bool {
+	return false
+}
+
+var signalNames = map[string]syscall.Signal{
+	"ABRT": syscall.SIGABRT,
+	"ALRM": syscall.SIGALRM,
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"KILL": syscall.SIGKILL,
+	"PIPE": syscall.SIGPIPE,
+	"QUIT": syscall.SIGQUIT,
+	"TERM": syscall.SIGTERM,
+}
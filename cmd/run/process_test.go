@@ -0,0 +1,80 @@
+//go:build unix
+
+package run
+
+import (
+	"context"
+	"github.com/coder/quartz"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// runAdvancingClock runs the command while moving the clock on by the poll interval of the cleanup until it finishes
+func runAdvancingClock(t *testing.T, r *Run, clock *quartz.Mock) error {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() {
+		done <- r.Run(&Config{})
+	}()
+
+	for {
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(10 * time.Millisecond):
+			clock.Advance(groupPollInterval).MustWait(context.Background())
+		}
+	}
+}
+
+func TestLeftoverProcesses(t *testing.T) {
+	t.Parallel()
+	r, clock := newSampleRun(t, "sh", "-c", "sleep 30 & echo started")
+	r.KillGracePeriod = time.Hour
+
+	require.NoError(t, runAdvancingClock(t, r, clock))
+
+	completed := lastUpdate(t, r)
+	require.Contains(t, completed.GetOutput().GetText(), "started")
+	require.Contains(t, completed.GetOutput().GetSummary(), "Leftover processes: `terminated`")
+}
+
+func TestLeftoverProcessesKilled(t *testing.T) {
+	t.Parallel()
+	r, clock := newSampleRun(t, "sh", "-c", "(trap '' TERM; sleep 30) & echo started")
+	r.KillGracePeriod = 2 * groupPollInterval
+
+	require.NoError(t, runAdvancingClock(t, r, clock))
+	completed := lastUpdate(t, r)
+	require.Contains(t, completed.GetOutput().GetSummary(), "Leftover processes: `killed`")
+}
+
+func TestNoLeftoverProcesses(t *testing.T) {
+	t.Parallel()
+	r, _ := newSampleRun(t, command(t, "echo", "alone")...)
+
+	require.NoError(t, r.Run(&Config{}))
+	completed := lastUpdate(t, r)
+	require.NotContains(t, completed.GetOutput().GetSummary(), "Leftover processes")
+}
+
+func TestCreateFailureKillsCommand(t *testing.T) {
+	t.Parallel()
+	r, _ := newSampleRun(t, "sh", "-c", "echo $$; exec sleep 60")
+	pid := 0
+	getCheckServiceOutFromRun(t, r).Fail = func(string) error {
+		require.Eventually(t, func() bool {
+			pid, _ = strconv.Atoi(strings.TrimSpace(r.screen.ReadScreen()))
+			return pid > 0
+		}, 10*time.Second, 5*time.Millisecond)
+		return errors.New("rejected")
+	}
+
+	require.ErrorContains(t, r.Run(&Config{}), "rejected")
+	require.ErrorIs(t, syscall.Kill(pid, 0), syscall.ESRCH)
+}
@@ -0,0 +1,56 @@
+//go:build unix
+
+package run
+
+import (
+	"github.com/pkg/errors"
+	"os"
+	"syscall"
+)
+
+// groupAttr returns the attributes starting the command as the leader of its own process group
+func groupAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setpgid: true}
+}
+
+// signalGroup sends the signal to every process of the group led by the process of the given ID
+func signalGroup(pid int, s os.Signal) error {
+	sig, ok := s.(syscall.Signal)
+	if !ok {
+		return errors.Errorf("unsupported signal %v", s)
+	}
+
+	err := syscall.Kill(-pid, sig)
+	if errors.Is(err, syscall.ESRCH) {
+		return errors.WithStack(os.ErrProcessDone)
+	}
+	return errors.WithStack(err)
+}
+
+// groupAlive tells whether any process of the group led by the process of the given ID is left
+func groupAlive(pid int) bool {
+	// EPERM means there is a process, only not ours to signal
+	err := syscall.Kill(-pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// signalNames are the signals which can be named in the signal options
+var signalNames = map[string]syscall.Signal{
+	"ABRT":  syscall.SIGABRT,
+	"ALRM":  syscall.SIGALRM,
+	"CHLD":  syscall.SIGCHLD,
+	"CONT":  syscall.SIGCONT,
+	"HUP":   syscall.SIGHUP,
+	"INT":   syscall.SIGINT,
+	"KILL":  syscall.SIGKILL,
+	"PIPE":  syscall.SIGPIPE,
+	"QUIT":  syscall.SIGQUIT,
+	"TERM":  syscall.SIGTERM,
+	"TSTP":  syscall.SIGTSTP,
+	"TTIN":  syscall.SIGTTIN,
+	"TTOU":  syscall.SIGTTOU,
+	"URG":   syscall.SIGURG,
+	"USR1":  syscall.SIGUSR1,
+	"USR2":  syscall.SIGUSR2,
+	"WINCH": syscall.SIGWINCH,
+}
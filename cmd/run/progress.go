@@ -0,0 +1,109 @@
+package run
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	progressGroupDone  = "done"
+	progressGroupTotal = "total"
+	progressFormat     = "%s — %s"
+	// progressScanLimit is the length of the end of the output searched for the latest progress
+	progressScanLimit = 64 * 1024
+)
+
+// compileProgressPattern compiles the progress pattern, which must capture at least the done group
+func compileProgressPattern(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing --progress-pattern")
+	}
+
+	if re.SubexpIndex(progressGroupDone) < 0 {
+		return nil, errors.Errorf("--progress-pattern must have a named group (?P<%s>...)", progressGroupDone)
+	}
+
+	return re, nil
+}
+
+// tailText returns the last lines of the output of the command, or of the outputs of the steps one after another,
+// fitting in n bytes. Only the tail of the screens is read so that its cost does not depend on the length of the output
+func (r *Run) tailText(n int) string {
+	screens := []*SyncScreen{r.screen}
+	if len(r.steps) > 0 {
+		screens = make([]*SyncScreen, 0, len(r.steps))
+		for _, s := range r.steps {
+			screens = append(screens, s.screen)
+		}
+	}
+
+	texts := make([]string, 0, 1)
+	size := 0
+	for i := len(screens) - 1; i >= 0 && size < n; i-- {
+		text := screens[i].ReadTail(n - size)
+		texts = append(texts, text)
+		size += len(text) + 1
+	}
+	slices.Reverse(texts)
+	text := strings.Join(texts, "\n")
+
+	// the first line is dropped when it was cut
+	if size > n {
+		if i := strings.IndexByte(text, '\n'); i >= 0 {
+			text = text[i+1:]
+		}
+	}
+	return text
+}
+
+// getTitle returns the title of the check run, followed by the latest progress found in the output if any
+func (r *Run) getTitle() string {
+	if r.progressPattern == nil {
+		return r.Title
+	}
+
+	progress := findProgress(r.progressPattern, r.tailText(progressScanLimit))
+	if progress == "" {
+		return r.Title
+	}
+
+	return fmt.Sprintf(progressFormat, r.Title, progress)
+}
+
+// findProgress returns the progress of the last line matching the pattern, as done/total (percentage)
+// when the total is captured as well
+func findProgress(re *regexp.Regexp, text string) string {
+	lines := strings.Split(text, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		match := re.FindStringSubmatch(lines[i])
+		if match == nil {
+			continue
+		}
+
+		done := match[re.SubexpIndex(progressGroupDone)]
+		if done == "" {
+			continue
+		}
+
+		totalIndex := re.SubexpIndex(progressGroupTotal)
+		if totalIndex < 0 || match[totalIndex] == "" {
+			return done
+		}
+
+		total := match[totalIndex]
+		progress := done + "/" + total
+		d, doneErr := strconv.ParseFloat(done, 64)
+		t, totalErr := strconv.ParseFloat(total, 64)
+		if doneErr == nil && totalErr == nil && t > 0 {
+			progress += fmt.Sprintf(" (%d%%)", int(d*100/t))
+		}
+		return progress
+	}
+
+	return ""
+}
@@ -0,0 +1,67 @@
+package run
+
+import (
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+)
+
+func TestFindProgress(t *testing.T) {
+	t.Parallel()
+	re := regexp.MustCompile(`(?P<done>\d+)/(?P<total>\d+)`)
+	tests := []struct {
+		name string
+		re   *regexp.Regexp
+		text string
+		want string
+	}{
+		{name: "no match", re: re, text: "starting\nrunning", want: ""},
+		{name: "last match", re: re, text: "1/900\n312/900\nsome output", want: "312/900 (34%)"},
+		{name: "zero total", re: re, text: "0/0", want: "0/0"},
+		{name: "done only", re: regexp.MustCompile(`passed: (?P<done>\d+)`), text: "passed: 12", want: "12"},
+		{name: "optional total", re: regexp.MustCompile(`(?P<done>\d+)(?: of (?P<total>\d+))?`), text: "3 of 4\n5", want: "5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, findProgress(tt.re, tt.text))
+		})
+	}
+}
+
+func TestCompileProgressPattern(t *testing.T) {
+	t.Parallel()
+	_, err := compileProgressPattern(`(\d+)/(\d+)`)
+	require.ErrorContains(t, err, "(?P<done>...)")
+
+	_, err = compileProgressPattern(`(?P<done>\d+`)
+	require.ErrorContains(t, err, "error parsing --progress-pattern")
+}
+
+func TestProgressPattern(t *testing.T) {
+	t.Parallel()
+	r, _ := newSampleRun(t, command(t, "prints", "1/900", "312/900", "done")...)
+	r.ProgressPattern = `(?P<done>\d+)/(?P<total>\d+)`
+
+	require.NoError(t, r.Run(&Config{}))
+	completed := lastUpdate(t, r)
+	require.Equal(t, sampleTitle+" — 312/900 (34%)", completed.GetOutput().GetTitle())
+}
+
+func TestTailText(t *testing.T) {
+	t.Parallel()
+	newScreen := func(text string) *SyncScreen {
+		screen, err := NewSyncScreen()
+		require.NoError(t, err)
+		_, err = screen.Write([]byte(text))
+		require.NoError(t, err)
+		return screen
+	}
+
+	r := &Run{screen: newScreen("first\nsecond\nthird")}
+	require.Equal(t, "first\nsecond\nthird", r.tailText(100))
+	require.Equal(t, "third", r.tailText(8))
+
+	r.steps = []*stepState{{screen: newScreen("one\ntwo")}, {screen: newScreen("three")}}
+	require.Equal(t, "one\ntwo\nthree", r.tailText(100))
+	require.Equal(t, "two\nthree", r.tailText(10))
+}
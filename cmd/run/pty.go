@@ -0,0 +1,29 @@
+package run
+
+import (
+	"github.com/pkg/errors"
+	"strconv"
+	"strings"
+)
+
+// ptyEOF is the end of file character of the pseudo-terminal, ^D
+const ptyEOF = 0x04
+
+// parsePtySize parses the size of the pseudo-terminal in the form of COLSxROWS
+func parsePtySize(size string) (int, int, error) {
+	colsText, rowsText, found := strings.Cut(strings.ToLower(size), "x")
+	if !found {
+		return 0, 0, errors.Errorf("invalid pty size %q, expected COLSxROWS", size)
+	}
+
+	cols, err := strconv.ParseUint(colsText, 10, 16)
+	if err != nil || cols == 0 {
+		return 0, 0, errors.Errorf("invalid pty size %q, expected COLSxROWS", size)
+	}
+	rows, err := strconv.ParseUint(rowsText, 10, 16)
+	if err != nil || rows == 0 {
+		return 0, 0, errors.Errorf("invalid pty size %q, expected COLSxROWS", size)
+	}
+
+	return int(cols), int(rows), nil
+}
@@ -0,0 +1,75 @@
+//go:build linux
+
+package run
+
+import (
+	"github.com/pkg/errors"
+	"os"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+// ptySupported tells whether --pty is supported on this platform
+const ptySupported = true
+
+// openPty opens a pseudo-terminal of the given size, returning its master and slave ends
+func openPty(cols int, rows int) (*os.File, *os.File, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error opening pseudo-terminal")
+	}
+
+	var number uint32
+	err = ioctl(master, syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(new(int32))))
+	if err == nil {
+		err = ioctl(master, syscall.TIOCGPTN, uintptr(unsafe.Pointer(&number)))
+	}
+	if err != nil {
+		_ = master.Close()
+		return nil, nil, errors.Wrap(err, "error unlocking pseudo-terminal")
+	}
+
+	slave, err := os.OpenFile("/dev/pts/"+strconv.FormatUint(uint64(number), 10), os.O_RDWR|syscall.O_NOCTTY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		_ = master.Close()
+		return nil, nil, errors.Wrap(err, "error opening pseudo-terminal")
+	}
+
+	size := &struct {
+		rows, cols, x, y uint16
+	}{rows: uint16(rows), cols: uint16(cols)}
+	err = ioctl(slave, syscall.TIOCSWINSZ, uintptr(unsafe.Pointer(size)))
+	if err != nil {
+		_ = master.Close()
+		_ = slave.Close()
+		return nil, nil, errors.Wrap(err, "error sizing pseudo-terminal")
+	}
+
+	return master, slave, nil
+}
+
+// ioctl runs the request on the file without taking it out of the non-blocking mode of the runtime poller
+func ioctl(f *os.File, request uintptr, arg uintptr) error {
+	conn, err := f.SyscallConn()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	var errno syscall.Errno
+	err = conn.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, request, arg)
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if errno != 0 {
+		return errors.WithStack(errno)
+	}
+	return nil
+}
+
+// ptyAttr returns the attributes making the pseudo-terminal given as stdin the controlling terminal of the command
+func ptyAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true, Setctty: true}
+}
@@ -0,0 +1,69 @@
+//go:build linux
+
+package run
+
+import (
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPty(t *testing.T) {
+	t.Parallel()
+	r, _ := newSampleRun(t, "sh", "-c", "test -t 1 && echo tty; stty size")
+	r.Pty = true
+	r.PtySize = "100x30"
+
+	require.NoError(t, r.Run(&Config{}))
+	completed := lastUpdate(t, r)
+	text := completed.GetOutput().GetText()
+	require.True(t, strings.Contains(text, "tty"), text)
+	require.True(t, strings.Contains(text, "30 100"), text)
+	require.Equal(t, checksConclusionSuccess, completed.GetConclusion())
+}
+
+func TestPtyFailure(t *testing.T) {
+	t.Parallel()
+	r, _ := newSampleRun(t, "sh", "-c", "echo before; exit 3")
+	r.Pty = true
+	r.PtySize = "80x24"
+
+	require.Error(t, r.Run(&Config{}))
+	completed := lastUpdate(t, r)
+	require.True(t, strings.Contains(completed.GetOutput().GetText(), "before"))
+	require.Equal(t, checksConclusionFailure, completed.GetConclusion())
+}
+
+func TestPtyStdin(t *testing.T) {
+	t.Parallel()
+	r, _ := newSampleRun(t, command(t, "upper")...)
+	r.Pty = true
+	r.PtySize = "80x24"
+	r.stdin = strings.NewReader("typed\nin")
+
+	require.NoError(t, r.Run(&Config{}))
+	completed := lastUpdate(t, r)
+	text := completed.GetOutput().GetText()
+	require.True(t, strings.Contains(text, "TYPED\nIN"), text)
+}
+
+func TestPtyNoStdin(t *testing.T) {
+	t.Parallel()
+	r, _ := newSampleRun(t, command(t, "upper")...)
+	r.Pty = true
+	r.PtySize = "80x24"
+	r.stdin = strings.NewReader("ignored\n")
+	r.NoStdin = true
+
+	done := make(chan error, 1)
+	go startCommand(t, r, done)
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("the command never got the end of its input")
+	}
+	completed := lastUpdate(t, r)
+	require.NotContains(t, completed.GetOutput().GetText(), "IGNORED")
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+
+package run
+
+import (
+	"github.com/pkg/errors"
+	"os"
+	"syscall"
+)
+
+// ptySupported tells whether --pty is supported on this platform
+const ptySupported = false
+
+func openPty(int, int) (*os.File, *os.File, error) {
+	return nil, nil, errors.New("pseudo-terminals are only supported on linux")
+}
+
+func ptyAttr() *syscall.SysProcAttr {
+	return nil
+}
@@ -0,0 +1,113 @@
+package run
+
+import (
+	"fmt"
+	"github.com/alecthomas/kong"
+	"github.com/coder/quartz"
+	"github.com/pkg/errors"
+	"io"
+	"os"
+)
+
+// Queue is the struct for the queue command, it creates queued check runs to announce the checks coming up
+type Queue struct {
+	Owner      string   `short:"o" env:"CHECKS4SHELL_OWNER" required:"" help:"The owner of the target GitHub repo"`
+	Repository string   `short:"r" env:"CHECKS4SHELL_REPOSITORY" required:"" help:"The target GitHub repository"`
+	CommitSHA  string   `short:"c" env:"CHECKS4SHELL_COMMIT_SHA" required:"" help:"The target SHA of the check Runs to be created"`
+	Manifest   string   `short:"m" env:"CHECKS4SHELL_MANIFEST" type:"existingfile" help:"JSON manifest file in the same format as the multi command, every check in it is queued"`
+	Debug      bool     `short:"d" help:"Enable debug mode"`
+	Names      []string `arg:"" optional:"" help:"Names of the checks to queue"`
+	MaskFlags
+
+	clock quartz.Clock
+
+	stdout          io.Writer
+	checksService   ChecksService
+	isAuthenticated bool
+}
+
+// AfterApply will run on CLI and initialise the missing properties
+func (q *Queue) AfterApply(_ *kong.Context, cfg *Config) error {
+	if q.clock == nil {
+		q.clock = quartz.NewReal()
+	}
+
+	if q.stdout == nil {
+		q.stdout = os.Stdout
+	}
+
+	q.checksService = cfg.ChecksService
+	q.isAuthenticated = cfg.IsAuthenticated
+
+	return nil
+}
+
+// Run creates the queued check runs and prints out their IDs, to be given to the run command as --check-run-id
+func (q *Queue) Run() error {
+	var checks []*MultiCheck
+	if q.Manifest != "" {
+		fromManifest, err := readManifest(q.Manifest)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		checks = append(checks, fromManifest...)
+	}
+
+	for _, name := range q.Names {
+		checks = append(checks, &MultiCheck{Name: name})
+	}
+
+	if len(checks) == 0 {
+		return errors.New("either check names or --manifest is required")
+	}
+
+	for _, check := range checks {
+		id, err := q.queue(check)
+		if err != nil {
+			return errors.Wrapf(err, "error queueing check %s", check.Name)
+		}
+
+		_, err = fmt.Fprintf(q.stdout, "%s\t%d\n", check.Name, id)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	return nil
+}
+
+func (q *Queue) queue(check *MultiCheck) (int64, error) {
+	screen, err := NewSyncScreen()
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	title := check.Title
+	if title == "" {
+		title = check.Name
+	}
+
+	r := &Run{
+		Owner:           q.Owner,
+		Repository:      q.Repository,
+		CommitSHA:       q.CommitSHA,
+		Name:            check.Name,
+		Title:           title,
+		DetailsURL:      check.DetailsURL,
+		ExternalID:      check.ExternalID,
+		Summary:         check.Summary,
+		Debug:           q.Debug,
+		screen:          screen,
+		clock:           q.clock,
+		checksService:   q.checksService,
+		isAuthenticated: q.isAuthenticated,
+		masker:          q.newMasker(),
+	}
+
+	err = r.queueCheckRun()
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	return r.runId, nil
+}
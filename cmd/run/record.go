@@ -0,0 +1,225 @@
+package run
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/alecthomas/kong"
+	"github.com/google/go-github/v64/github"
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	recordKindCreate = "create"
+	recordKindUpdate = "update"
+	// recordLineLimit is the longest line of a record file, well above the limits of the check run output
+	recordLineLimit = 16 * 1024 * 1024
+)
+
+// recordedPayload is a line of the record file, a payload sent to the checks API along with where it was sent
+type recordedPayload struct {
+	Time       time.Time                     `json:"time"`
+	Kind       string                        `json:"kind"`
+	Owner      string                        `json:"owner"`
+	Repository string                        `json:"repository"`
+	CheckRunID int64                         `json:"check_run_id"`
+	StartedAt  *time.Time                    `json:"started_at,omitempty"`
+	Create     *github.CreateCheckRunOptions `json:"create,omitempty"`
+	Update     *github.UpdateCheckRunOptions `json:"update,omitempty"`
+}
+
+// record appends the payload to the record file if there is one, whether or not it was sent
+func (r *Run) record(p *recordedPayload) error {
+	r.stamp(p)
+	if r.Record == "" {
+		return nil
+	}
+
+	line, err := json.Marshal(p)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	f, err := os.OpenFile(r.Record, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrap(err, "error opening record file")
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	_, err = f.Write(append(line, '\n'))
+	if err != nil {
+		return errors.Wrap(err, "error writing record file")
+	}
+
+	return errors.Wrap(f.Close(), "error writing record file")
+}
+
+// stamp sets when and where the payload is sent
+func (r *Run) stamp(p *recordedPayload) {
+	p.Time = r.clock.Now()
+	p.Owner = r.Owner
+	p.Repository = r.Repository
+	p.CheckRunID = r.runId
+}
+
+// Replay is the struct for the replay command, it sends the payloads of a record file again
+type Replay struct {
+	Owner      string `short:"o" help:"Owner of the GitHub repo to replay to instead of the recorded one"`
+	Repository string `short:"r" help:"GitHub repository to replay to instead of the recorded one"`
+	CommitSHA  string `short:"c" help:"SHA to create the check runs on instead of the recorded one"`
+	Debug      bool   `short:"d" help:"Enable debug mode"`
+	File       string `arg:"" type:"existingfile" help:"Record file written by the run command with --record"`
+
+	stdout          io.Writer
+	checksService   ChecksService
+	isAuthenticated bool
+}
+
+// AfterApply will run on CLI and initialise the missing properties
+func (p *Replay) AfterApply(_ *kong.Context, cfg *Config) error {
+	if p.stdout == nil {
+		p.stdout = os.Stdout
+	}
+
+	p.checksService = cfg.ChecksService
+	p.isAuthenticated = cfg.IsAuthenticated
+
+	return nil
+}
+
+// Run sends the recorded payloads in order, the updates of a recorded check run going to the one created in its place
+func (p *Replay) Run() error {
+	if !p.isAuthenticated && !p.Debug {
+		return errors.New("replaying requires the GitHub App credentials, or --debug to print the payloads")
+	}
+
+	f, err := os.Open(p.File)
+	if err != nil {
+		return errors.Wrap(err, "error opening record file")
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	// recorded check run ID to the ID of the check run created in its place
+	ids := make(map[int64]int64)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), recordLineLimit)
+	for line := 1; scanner.Scan(); line++ {
+		rec := &recordedPayload{}
+		err = json.Unmarshal(scanner.Bytes(), rec)
+		if err != nil {
+			return errors.Wrapf(err, "error parsing line %d of the record file", line)
+		}
+
+		err = p.send(rec, ids)
+		if err != nil {
+			return errors.Wrapf(err, "error replaying line %d of the record file", line)
+		}
+	}
+
+	return errors.Wrap(scanner.Err(), "error reading record file")
+}
+
+func (p *Replay) send(rec *recordedPayload, ids map[int64]int64) error {
+	owner, repo := rec.Owner, rec.Repository
+	if p.Owner != "" {
+		owner = p.Owner
+	}
+	if p.Repository != "" {
+		repo = p.Repository
+	}
+
+	if rec.Kind == recordKindCreate && p.CommitSHA != "" && rec.Create != nil {
+		rec.Create.HeadSHA = p.CommitSHA
+	}
+
+	id, ok := ids[rec.CheckRunID]
+	if !ok {
+		// the check run existed before the recording
+		id = rec.CheckRunID
+	}
+
+	if !p.isAuthenticated {
+		if rec.Kind == recordKindCreate {
+			id = -1
+			ids[rec.CheckRunID] = id
+		}
+		return errors.WithStack(p.debug(owner, repo, id, rec))
+	}
+
+	created, err := sendRecordedPayload(p.checksService, owner, repo, id, rec)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if rec.Kind == recordKindCreate {
+		ids[rec.CheckRunID] = created
+		return p.log("created %s as check run %d\n", rec.Create.Name, created)
+	}
+
+	return nil
+}
+
+// sendRecordedPayload sends the recorded payload, creating a check run and returning its ID for a create payload,
+// updating the check run of the given ID otherwise
+func sendRecordedPayload(service ChecksService, owner string, repo string, id int64, rec *recordedPayload) (int64, error) {
+	switch rec.Kind {
+	case recordKindCreate:
+		if rec.Create == nil {
+			return 0, errors.New("create record without payload")
+		}
+
+		checkRun, _, err := service.CreateCheckRun(context.Background(), owner, repo, *rec.Create)
+		if err != nil {
+			return 0, errors.Wrap(err, "error creating check Run")
+		}
+		return checkRun.GetID(), nil
+	case recordKindUpdate:
+		if rec.Update == nil {
+			return 0, errors.New("update record without payload")
+		}
+		if id <= 0 {
+			return 0, errors.New("update record of a check run never created")
+		}
+
+		starter, canStart := service.(checkRunStarter)
+		var err error
+		if rec.StartedAt != nil && canStart {
+			_, _, err = starter.StartCheckRun(context.Background(), owner, repo, id, *rec.StartedAt, *rec.Update)
+		} else {
+			_, _, err = service.UpdateCheckRun(context.Background(), owner, repo, id, *rec.Update)
+		}
+		if err != nil {
+			return 0, errors.Wrapf(err, "error updating check Run %d", id)
+		}
+		return id, nil
+	default:
+		return 0, errors.Errorf("unknown record kind %q", rec.Kind)
+	}
+}
+
+func (p *Replay) debug(owner string, repo string, id int64, rec *recordedPayload) error {
+	var payload any = rec.Update
+	if rec.Kind == recordKindCreate {
+		payload = rec.Create
+	}
+
+	marshalled, err := marshal(payload)
+	if err != nil {
+		return errors.Wrapf(err, "error marshaling checkRun")
+	}
+
+	return p.log("Sending %s/%s, %d, %s\n", owner, repo, id, marshalled)
+}
+
+func (p *Replay) log(format string, args ...any) error {
+	_, err := fmt.Fprintf(p.stdout, format, args...)
+	return errors.WithStack(err)
+}
@@ -0,0 +1,108 @@
+package run
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"github.com/coder/quartz"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readRecords(t *testing.T, path string) []*recordedPayload {
+	t.Helper()
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var records []*recordedPayload
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		rec := &recordedPayload{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), rec))
+		records = append(records, rec)
+	}
+	require.NoError(t, scanner.Err())
+	return records
+}
+
+func TestRecordWithoutCredentials(t *testing.T) {
+	t.Parallel()
+	record := filepath.Join(t.TempDir(), "payloads.jsonl")
+	r, clock := newSampleRun(t, command(t, "echo", "recorded")...)
+	r.isAuthenticated = false
+	r.Record = record
+
+	require.NoError(t, r.Run(&Config{}))
+	require.Empty(t, getCheckServiceOutFromRun(t, r).Calls())
+
+	records := readRecords(t, record)
+	require.Len(t, records, 2)
+	require.Equal(t, recordKindCreate, records[0].Kind)
+	require.Equal(t, sampleName, records[0].Create.Name)
+	require.Equal(t, sampleOwner, records[0].Owner)
+	require.Equal(t, sampleRepo, records[0].Repository)
+	require.True(t, clock.Now().Equal(records[0].Time))
+	require.Equal(t, recordKindUpdate, records[1].Kind)
+	require.Equal(t, checksConclusionSuccess, records[1].Update.GetConclusion())
+	require.Contains(t, records[1].Update.GetOutput().GetText(), "recorded")
+}
+
+func TestReplay(t *testing.T) {
+	t.Parallel()
+	record := filepath.Join(t.TempDir(), "payloads.jsonl")
+	r, _ := newSampleRun(t, command(t, "echo", "replayed")...)
+	r.Record = record
+	require.NoError(t, r.Run(&Config{}))
+	recorded := getCheckServiceOutFromRun(t, r).Calls()
+
+	stdout := &bytes.Buffer{}
+	service := newMockChecksService(r.clock, 77)
+	p := &Replay{
+		Repository:      "otherRepo",
+		File:            record,
+		stdout:          stdout,
+		checksService:   service,
+		isAuthenticated: true,
+	}
+	require.NoError(t, p.Run())
+	require.Equal(t, "created "+sampleName+" as check run 77\n", stdout.String())
+
+	replayed := service.Calls()
+	require.Len(t, replayed, len(recorded))
+	for i := range replayed {
+		require.Equal(t, sampleOwner, replayed[i].Owner)
+		require.Equal(t, "otherRepo", replayed[i].Repository)
+		require.Equal(t, int64(77), replayed[i].CheckRunID)
+	}
+	// timestamps go through JSON in the record file, compare the payloads the same way
+	want, err := json.Marshal(recorded[len(recorded)-1].Options)
+	require.NoError(t, err)
+	got, err := json.Marshal(replayed[len(replayed)-1].Options)
+	require.NoError(t, err)
+	require.JSONEq(t, string(want), string(got))
+}
+
+func TestReplayRequiresCredentials(t *testing.T) {
+	t.Parallel()
+	p := &Replay{File: filepath.Join(t.TempDir(), "payloads.jsonl")}
+	require.ErrorContains(t, p.Run(), "replaying requires the GitHub App credentials")
+}
+
+func TestReplayUpdateOfUnknownCheckRun(t *testing.T) {
+	t.Parallel()
+	record := filepath.Join(t.TempDir(), "payloads.jsonl")
+	require.NoError(t, os.WriteFile(record, []byte(`{"kind":"update","check_run_id":0,"update":{"name":"n"}}`+"\n"), 0600))
+
+	p := &Replay{
+		File:            record,
+		stdout:          &bytes.Buffer{},
+		checksService:   NewMockChecksService(quartz.NewMock(t)),
+		isAuthenticated: true,
+	}
+	require.ErrorContains(t, p.Run(), "error replaying line 1 of the record file: update record of a check run never created")
+}
@@ -0,0 +1,88 @@
+package run
+
+import (
+	_ "embed"
+	"github.com/pkg/errors"
+	"html/template"
+	"os"
+	"strings"
+)
+
+//go:embed report.html
+var reportTemplateContent string
+
+var reportTemplate = template.Must(template.New("report").Parse(reportTemplateContent))
+
+// reportData is the data given to the HTML report template
+type reportData struct {
+	Name       string
+	Title      string
+	Command    string
+	Duration   string
+	ExitStatus string
+	Conclusion string
+	Sections   []reportSection
+}
+
+// reportSection is the rendered output of the command, or of a single step
+type reportSection struct {
+	Name   string
+	Output template.HTML
+}
+
+// writeHTMLReport writes a self-contained HTML page of the final output of the execution to path
+func (r *Run) writeHTMLReport(path string) error {
+	data := &reportData{
+		Name:  r.Name,
+		Title: r.Title,
+	}
+
+	if len(r.steps) > 0 {
+		commands := make([]string, 0, len(r.steps))
+		for _, s := range r.steps {
+			commands = append(commands, strings.Join(s.step.Command, " "))
+			data.Sections = append(data.Sections, reportSection{
+				Name: s.step.Name,
+				// the screen escapes the output it renders
+				Output: template.HTML(s.screen.ReadHTML()),
+			})
+		}
+		data.Command = r.masker.mask(strings.Join(commands, " && "))
+	} else {
+		data.Command = r.masker.mask(strings.Join(r.ShellCommand, " "))
+		data.Sections = []reportSection{{Output: template.HTML(r.screen.ReadHTML())}}
+		if r.inputScreen != nil {
+			data.Sections = []reportSection{
+				{Name: "input", Output: template.HTML(r.inputScreen.ReadHTML())},
+				{Name: "output", Output: data.Sections[0].Output},
+			}
+		}
+	}
+
+	r.resultLock.RLock()
+	data.ExitStatus = r.exitStatus
+	data.Conclusion = r.conclusion
+	if !r.finishedAt.IsZero() {
+		data.Duration = formatDuration(r.finishedAt.Sub(r.startedAt))
+	}
+	r.resultLock.RUnlock()
+
+	if data.Conclusion == "" {
+		data.Conclusion = "unknown"
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrap(err, "error creating HTML report")
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	err = reportTemplate.Execute(f, data)
+	if err != nil {
+		return errors.Wrap(err, "error writing HTML report")
+	}
+
+	return errors.Wrap(f.Close(), "error writing HTML report")
+}
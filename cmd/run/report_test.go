@@ -0,0 +1,51 @@
+package run
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHTMLReport(t *testing.T) {
+	t.Parallel()
+	report := filepath.Join(t.TempDir(), "report.html")
+	r, _ := newSampleRun(t, command(t, "echo", "\x1b[31mred\x1b[0m <b>")...)
+	r.HTMLReport = report
+
+	require.NoError(t, r.Run(&Config{}))
+	content, err := os.ReadFile(report)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "<h1>"+sampleName+" · "+sampleTitle+"</h1>")
+	require.Contains(t, string(content), `<span class="term-fg31">red</span> &lt;b&gt;`)
+	require.Contains(t, string(content), `<dt>Exit status</dt><dd>0</dd>`)
+	require.Contains(t, string(content), `<span class="conclusion conclusion-success">success</span>`)
+}
+
+func TestHTMLReportMasksCommand(t *testing.T) {
+	t.Parallel()
+	report := filepath.Join(t.TempDir(), "report.html")
+	r, _ := newSampleRun(t, command(t, "echo", "hidden-value")...)
+	r.HTMLReport = report
+	r.Mask = []string{"hidden-value"}
+
+	require.NoError(t, r.Run(&Config{}))
+	content, err := os.ReadFile(report)
+	require.NoError(t, err)
+	require.NotContains(t, string(content), "hidden-value")
+	require.Contains(t, string(content), "echo ***")
+}
+
+func TestHTMLReportOfFailure(t *testing.T) {
+	t.Parallel()
+	report := filepath.Join(t.TempDir(), "report.html")
+	r, _ := newSampleRun(t, command(t, "errorm", "3", "broken")...)
+	r.HTMLReport = report
+
+	require.Error(t, r.Run(&Config{}))
+	content, err := os.ReadFile(report)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "broken")
+	require.Contains(t, string(content), `<dt>Exit status</dt><dd>3</dd>`)
+	require.Contains(t, string(content), `<span class="conclusion conclusion-failure">failure</span>`)
+}
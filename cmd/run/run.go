@@ -5,40 +5,111 @@ import (
 	"fmt"
 	"github.com/alecthomas/kong"
 	"github.com/coder/quartz"
+	"github.com/google/go-github/v64/github"
 	"github.com/pkg/errors"
 	"io"
 	"os"
 	"os/exec"
 	"os/signal"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"text/template"
 	"time"
 )
 
 // Run is the struct for the run command
 type Run struct {
-	Owner           string        `short:"o" env:"CHECKS4SHELL_OWNER" required:"" help:"The owner of the target GitHub repo"`
-	Repository      string        `short:"r" env:"CHECKS4SHELL_REPOSITORY" required:"" help:"The target GitHub repository"`
-	CommitSHA       string        `short:"c" env:"CHECKS4SHELL_COMMIT_SHA" required:"" help:"The target SHA of the check Run to be created"`
-	Name            string        `short:"n" env:"CHECKS4SHELL_NAME" required:"" help:"Name of the check Run"`
-	Title           string        `short:"t" env:"CHECKS4SHELL_TITLE" required:"" help:"Output title of the check"`
-	DetailsURL      string        `short:"u" env:"CHECKS4SHELL_DETAILS_URL" help:"Details URL of the check" `
-	ExternalID      string        `short:"e" env:"CHECKS4SHELL_EXTERNAL_ID" help:"External ID of the check" `
-	Summary         string        `short:"s" env:"CHECKS4SHELL_SUMMARY" help:"Output summary of the check can either be a fixed string or a file filled with content"`
-	Images          string        `short:"i" env:"CHECKS4SHELL_IMAGES" help:"Output image json directory of the check, files inside will be presented in naming order. the json structure is the same as github.CheckRunImage"`
-	Annotations     string        `short:"a" env:"CHECKS4SHELL_ANNOTATIONS" help:"Output annotation directory of the check, files inside will be presented in naming order. the json structure is the same as github.CheckRunAnnotation"`
-	UpdateFrequency time.Duration `short:"f" env:"CHECKS4SHELL_UPDATE_FREQUENCY" help:"Frequency to update the check run" default:"5s"`
-	SyntaxHighlight string        `short:"l" env:"CHECKS4SHELL_SYNTAX_HIGHLIGHT" help:"syntax highlight you want to use for the terminal output"`
-	Debug           bool          `short:"d" help:"Enable debug mode"`
-	ShellCommand    []string      `arg:"" help:"Shell commands to Run and filling the check Run output text" required:""`
-
-	screen *SyncScreen
-	clock  quartz.Clock
+	Owner                string            `short:"o" env:"CHECKS4SHELL_OWNER" required:"" help:"The owner of the target GitHub repo"`
+	Repository           string            `short:"r" env:"CHECKS4SHELL_REPOSITORY" required:"" help:"The target GitHub repository"`
+	CommitSHA            string            `short:"c" env:"CHECKS4SHELL_COMMIT_SHA" required:"" help:"The target SHA of the check Run to be created"`
+	Name                 string            `short:"n" env:"CHECKS4SHELL_NAME" required:"" help:"Name of the check Run"`
+	Title                string            `short:"t" env:"CHECKS4SHELL_TITLE" required:"" help:"Output title of the check"`
+	DetailsURL           string            `short:"u" env:"CHECKS4SHELL_DETAILS_URL" help:"Details URL of the check" `
+	ExternalID           string            `short:"e" env:"CHECKS4SHELL_EXTERNAL_ID" help:"External ID of the check" `
+	Summary              string            `short:"s" env:"CHECKS4SHELL_SUMMARY" help:"Output summary of the check can either be a fixed string or a file filled with content"`
+	Images               string            `short:"i" env:"CHECKS4SHELL_IMAGES" help:"Output image json directory of the check, files inside will be presented in naming order. the json structure is the same as github.CheckRunImage"`
+	Annotations          string            `short:"a" env:"CHECKS4SHELL_ANNOTATIONS" help:"Output annotation directory of the check, files inside will be presented in naming order. the json structure is the same as github.CheckRunAnnotation"`
+	UpdateFrequency      time.Duration     `short:"f" env:"CHECKS4SHELL_UPDATE_FREQUENCY" help:"Frequency to update the check run" default:"5s"`
+	AdaptiveUpdates      bool              `env:"CHECKS4SHELL_ADAPTIVE_UPDATES" help:"Update the check run as often as the output changes, between --min-update-frequency and --max-update-frequency, and slower when the GitHub rate limit runs low, instead of every --update-frequency"`
+	MinUpdateFrequency   time.Duration     `env:"CHECKS4SHELL_MIN_UPDATE_FREQUENCY" default:"2s" help:"Shortest interval between updates with --adaptive-updates"`
+	MaxUpdateFrequency   time.Duration     `env:"CHECKS4SHELL_MAX_UPDATE_FREQUENCY" default:"1m" help:"Longest interval between updates with --adaptive-updates"`
+	SyntaxHighlight      string            `short:"l" env:"CHECKS4SHELL_SYNTAX_HIGHLIGHT" help:"syntax highlight you want to use for the terminal output"`
+	Debug                bool              `short:"d" help:"Enable debug mode"`
+	Steps                string            `env:"CHECKS4SHELL_STEPS" type:"existingfile" help:"JSON file of steps, each with a name and a command, to run one after another instead of the shell command"`
+	ContinueOnError      bool              `env:"CHECKS4SHELL_CONTINUE_ON_ERROR" help:"Keep running the remaining steps after a step fails"`
+	Action               []string          `env:"CHECKS4SHELL_ACTION" sep:"none" help:"Action button of the check in the form of identifier:label:description, can be repeated up to 3 times"`
+	ActionsFile          string            `env:"CHECKS4SHELL_ACTIONS_FILE" type:"existingfile" help:"JSON file of the action buttons of the check, the json structure is a list of github.CheckRunAction"`
+	CheckRunID           int64             `env:"CHECKS4SHELL_CHECK_RUN_ID" help:"ID of an existing check run to update instead of creating a new one"`
+	ReuseByExternalID    bool              `env:"CHECKS4SHELL_REUSE_BY_EXTERNAL_ID" help:"Update the existing check run with the same external ID on the commit, if there is one, instead of creating a new one"`
+	CreateQueued         bool              `env:"CHECKS4SHELL_CREATE_QUEUED" help:"Create the check run as queued before the command starts, switching it to in progress once it does"`
+	SummaryTemplate      string            `env:"CHECKS4SHELL_SUMMARY_TEMPLATE" help:"Go template of the summary while the command is running, can either be a fixed string or a file filled with content"`
+	FinalSummaryTemplate string            `env:"CHECKS4SHELL_FINAL_SUMMARY_TEMPLATE" help:"Go template of the summary once the command finished, can either be a fixed string or a file filled with content, defaults to --summary-template"`
+	SummaryEnv           []string          `env:"CHECKS4SHELL_SUMMARY_ENV" help:"Environment variable the summary templates can read through .Env, can be repeated"`
+	HTMLReport           string            `env:"CHECKS4SHELL_HTML_REPORT" help:"Path of a self-contained HTML page of the final output written once the command finished"`
+	Record               string            `env:"CHECKS4SHELL_RECORD" help:"JSON lines file every create and update payload is appended to, whether or not GitHub credentials are given, to be sent again with the replay command"`
+	SpoolDir             string            `env:"CHECKS4SHELL_SPOOL_DIR" help:"Directory the last update is written to when GitHub can't be reached, to be sent later with the flush command. The command keeps running when updates fail in the meantime"`
+	ProgressPattern      string            `env:"CHECKS4SHELL_PROGRESS_PATTERN" help:"Regular expression with the named groups done and optionally total, the last line of the output matching it appends the progress to the title"`
+	NoExecutionFooter    bool              `env:"CHECKS4SHELL_NO_EXECUTION_FOOTER" help:"Do not append the duration, exit status and host of the execution to the summary"`
+	Pty                  bool              `env:"CHECKS4SHELL_PTY" help:"Run the command in a pseudo-terminal so that it keeps its colours and progress bars, linux only"`
+	PtySize              string            `env:"CHECKS4SHELL_PTY_SIZE" default:"80x24" help:"Size of the pseudo-terminal in the form of COLSxROWS"`
+	ForwardSignal        []string          `env:"CHECKS4SHELL_FORWARD_SIGNAL" default:"INT,TERM,HUP,QUIT,USR1,USR2" help:"Signal forwarded to the command, the others keep their default behaviour"`
+	TranslateSignal      map[string]string `env:"CHECKS4SHELL_TRANSLATE_SIGNAL" help:"Signal forwarded to the command as another one in the form of FROM=TO, e.g. TERM=INT, can be repeated"`
+	SecondSignal         string            `env:"CHECKS4SHELL_SECOND_SIGNAL" enum:"forward,kill" default:"forward" help:"What the second signal received does, either forward it like the first one or kill the command"`
+	NoResourceUsage      bool              `env:"CHECKS4SHELL_NO_RESOURCE_USAGE" help:"Do not append the max RSS, CPU time and context switches of the command to the summary"`
+	Scrollback           int               `env:"CHECKS4SHELL_SCROLLBACK" default:"10000" help:"Number of lines of output kept in memory for the check run and the HTML report, the older ones are discarded. 0 keeps them all"`
+	OutputFile           string            `env:"CHECKS4SHELL_OUTPUT_FILE" help:"File the whole output of the command is written to, whatever the scrollback"`
+	MaxOutputBytes       string            `env:"CHECKS4SHELL_MAX_OUTPUT_BYTES" help:"Largest output of the command, e.g. 10M, beyond which --on-max-output applies"`
+	MaxOutputLines       int64             `env:"CHECKS4SHELL_MAX_OUTPUT_LINES" help:"Largest number of lines of output of the command, beyond which --on-max-output applies"`
+	OnMaxOutput          string            `env:"CHECKS4SHELL_ON_MAX_OUTPUT" enum:"stop,kill" default:"stop" help:"What exceeding --max-output-bytes or --max-output-lines does, either stop showing the output in the check run or kill the command"`
+	MaxRSS               string            `env:"CHECKS4SHELL_MAX_RSS" help:"Largest resident set size the command may use, e.g. 512M or 2G, concluding the check run with --max-rss-conclusion when exceeded"`
+	MaxRSSConclusion     string            `env:"CHECKS4SHELL_MAX_RSS_CONCLUSION" enum:"failure,neutral" default:"failure" help:"Conclusion of the check run when the command exceeded --max-rss, either failure or neutral"`
+	KillGracePeriod      time.Duration     `env:"CHECKS4SHELL_KILL_GRACE_PERIOD" default:"5s" help:"Time the processes left behind by the command are given to exit once terminated, before they are killed"`
+	NoStdin              bool              `env:"CHECKS4SHELL_NO_STDIN" help:"Do not forward stdin to the command"`
+	TeeStdin             bool              `env:"CHECKS4SHELL_TEE_STDIN" help:"Show the input forwarded to the command in an input section of the output"`
+	ShellCommand         []string          `arg:"" optional:"" help:"Shell commands to Run and filling the check Run output text"`
+	MaskFlags
+
+	screen          *SyncScreen
+	inputScreen     *SyncScreen
+	stdin           io.Reader
+	ptyInput        io.Reader
+	clock           quartz.Clock
+	steps           []*stepState
+	progressPattern *regexp.Regexp
+	actions         []*github.CheckRunAction
+	masker          *masker
+	ptyCols         int
+	ptyRows         int
+
+	summaryTemplate      *template.Template
+	finalSummaryTemplate *template.Template
 
 	additionalWriters []io.Writer
+	env               []string
 	checksService     ChecksService
 	runId             int64
+	startedAt         time.Time
+	pendingStart      bool
+	finishedAt        time.Time
+	exitStatus        string
+	exitCode          int
+	leftovers         string
+	usage             *resourceUsage
+	maxRSS            int64
+	maxOutputBytes    int64
+	budget            *outputBudget
+	rate              github.Rate
+	conclusion        string
+	resultLock        sync.RWMutex
 	isAuthenticated   bool
 	sigChan           chan os.Signal
+	notifySignals     bool
+	signalPolicy      *signalPolicy
+	outputLimit       int
+	summaryLimit      int
 }
 
 // AfterApply will run on CLI and initialise the missing properties
@@ -56,6 +127,10 @@ func (r *Run) AfterApply(_ *kong.Context, cfg *Config) error {
 		r.clock = quartz.NewReal()
 	}
 
+	if r.stdin == nil {
+		r.stdin = os.Stdin
+	}
+
 	// if there is no additional writer, add stdout
 	if r.additionalWriters == nil {
 		r.additionalWriters = []io.Writer{os.Stdout}
@@ -64,15 +139,125 @@ func (r *Run) AfterApply(_ *kong.Context, cfg *Config) error {
 	r.checksService = cfg.ChecksService
 	r.isAuthenticated = cfg.IsAuthenticated
 
+	// notified of the signals to forward once they are known
 	r.sigChan = make(chan os.Signal, 1)
-	signal.Notify(r.sigChan)
+	r.notifySignals = true
+
+	return nil
+}
+
+// Options are what programs embedding checks4shell give to a run in place of the command line
+type Options struct {
+	// ChecksService sends the check run, nothing is sent without it
+	ChecksService ChecksService
+	// Clock drives the updates of the check run, the real clock by default
+	Clock quartz.Clock
+	// Writers are given the output of the command along with the check run
+	Writers []io.Writer
+	// OutputLimit is the length the output text is truncated to, the GitHub limit by default
+	OutputLimit int
+	// SummaryLimit is the length the summary is truncated to, the GitHub limit by default
+	SummaryLimit int
+	// Signals are forwarded to the command while it runs
+	Signals chan os.Signal
+	// Stdin is forwarded to the command, which gets no input without it
+	Stdin io.Reader
+}
+
+// Init initialises the missing properties from the options instead of the command line, the fields left to their
+// zero value take the defaults of their flags
+func (r *Run) Init(opts Options) error {
+	screen, err := NewSyncScreen()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	r.screen = screen
+
+	r.clock = opts.Clock
+	if r.clock == nil {
+		r.clock = quartz.NewReal()
+	}
+
+	r.additionalWriters = opts.Writers
+	r.checksService = opts.ChecksService
+	r.isAuthenticated = opts.ChecksService != nil
+	r.outputLimit = opts.OutputLimit
+	r.summaryLimit = opts.SummaryLimit
+
+	r.stdin = opts.Stdin
+	r.sigChan = opts.Signals
+	if r.sigChan == nil {
+		r.sigChan = make(chan os.Signal, 1)
+	}
+
+	r.applyDefaults()
 
 	return nil
 }
 
+// applyDefaults sets the fields left to their zero value to the defaults of their flags, for the runs not built by the CLI
+func (r *Run) applyDefaults() {
+	if r.UpdateFrequency == 0 {
+		r.UpdateFrequency = 5 * time.Second
+	}
+	if r.MinUpdateFrequency == 0 {
+		r.MinUpdateFrequency = 2 * time.Second
+	}
+	if r.MaxUpdateFrequency == 0 {
+		r.MaxUpdateFrequency = time.Minute
+	}
+	if r.PtySize == "" {
+		r.PtySize = "80x24"
+	}
+	if r.SecondSignal == "" {
+		r.SecondSignal = secondSignalForward
+	}
+	if r.Scrollback == 0 {
+		r.Scrollback = 10000
+	}
+	if r.OnMaxOutput == "" {
+		r.OnMaxOutput = outputFloodStop
+	}
+	if r.MaxRSSConclusion == "" {
+		r.MaxRSSConclusion = checksConclusionFailure
+	}
+	if r.KillGracePeriod == 0 {
+		r.KillGracePeriod = 5 * time.Second
+	}
+}
+
 // Run runs the command
 func (r *Run) Run(cfg *Config) error {
-	err := r.run()
+	err := r.prepare()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	var outputFile *os.File
+	if r.OutputFile != "" {
+		outputFile, err = os.Create(r.OutputFile)
+		if err != nil {
+			return errors.Wrap(err, "error creating output file")
+		}
+		defer func() {
+			_ = outputFile.Close()
+		}()
+		r.additionalWriters = append(r.additionalWriters, outputFile)
+	}
+
+	err = r.run()
+	if outputFile != nil {
+		closeErr := outputFile.Close()
+		if err == nil && closeErr != nil {
+			err = errors.Wrap(closeErr, "error writing output file")
+		}
+	}
+	if r.HTMLReport != "" {
+		reportErr := r.writeHTMLReport(r.HTMLReport)
+		if err == nil {
+			err = reportErr
+		}
+	}
 	if err != nil {
 		return errors.WithStack(err)
 	}
@@ -80,23 +265,148 @@ func (r *Run) Run(cfg *Config) error {
 	return nil
 }
 
+// prepare validates the flags and derives the state the run needs from them before anything starts
+func (r *Run) prepare() error {
+	if r.Steps != "" {
+		if len(r.ShellCommand) > 0 {
+			return errors.New("shell command and --steps cannot be used together")
+		}
+
+		steps, err := readSteps(r.Steps)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		r.steps = steps
+	} else if len(r.ShellCommand) == 0 {
+		return errors.New("either a shell command or --steps is required")
+	}
+
+	if r.AdaptiveUpdates && (r.MinUpdateFrequency <= 0 || r.MaxUpdateFrequency < r.MinUpdateFrequency) {
+		return errors.New("--adaptive-updates requires 0 < --min-update-frequency <= --max-update-frequency")
+	}
+
+	if r.ReuseByExternalID && r.ExternalID == "" {
+		return errors.New("--reuse-by-external-id requires an external ID")
+	}
+
+	if r.Pty {
+		if !ptySupported {
+			return errors.New("--pty is only supported on linux")
+		}
+
+		cols, rows, err := parsePtySize(r.PtySize)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		r.ptyCols, r.ptyRows = cols, rows
+	}
+
+	policy, err := newSignalPolicy(r.ForwardSignal, r.TranslateSignal, r.SecondSignal)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	r.signalPolicy = policy
+	if r.notifySignals {
+		signal.Notify(r.sigChan, policy.signals()...)
+	}
+
+	if r.MaxOutputBytes != "" {
+		maxOutputBytes, err := parseBytes(r.MaxOutputBytes)
+		if err != nil {
+			return errors.Wrap(err, "invalid --max-output-bytes")
+		}
+		r.maxOutputBytes = maxOutputBytes
+	}
+	r.budget = r.newOutputBudget()
+
+	if r.MaxRSS != "" {
+		maxRSS, err := parseBytes(r.MaxRSS)
+		if err != nil {
+			return errors.Wrap(err, "invalid --max-rss")
+		}
+		r.maxRSS = maxRSS
+	}
+
+	r.masker = r.newMasker()
+
+	if r.TeeStdin && len(r.steps) > 0 {
+		return errors.New("--tee-stdin cannot be used with --steps, the steps get no input")
+	}
+	if r.TeeStdin {
+		screen, err := NewSyncScreen()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		r.inputScreen = screen
+	}
+
+	if r.Scrollback > 0 {
+		for _, screen := range r.screens() {
+			err = screen.SetScrollback(r.Scrollback)
+			if err != nil {
+				return errors.Wrap(err, "error setting the scrollback")
+			}
+		}
+	}
+
+	// the actions and templates are parsed before anything starts so that an invalid one leaves no command running
+	r.actions, err = r.getActions()
+	if err != nil {
+		return errors.Wrap(err, "error getting actions")
+	}
+	r.summaryTemplate, err = parseSummaryTemplate(r.SummaryTemplate)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	r.finalSummaryTemplate, err = parseSummaryTemplate(r.FinalSummaryTemplate)
+	if err != nil {
+		return errors.Wrap(err, "invalid final summary template")
+	}
+
+	if r.ProgressPattern != "" {
+		re, err := compileProgressPattern(r.ProgressPattern)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		r.progressPattern = re
+	}
+
+	return nil
+}
+
 func (r *Run) run() error {
+	if r.CreateQueued {
+		err := r.queueCheckRun()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	if len(r.steps) > 0 {
+		return errors.WithStack(r.runSteps())
+	}
+
 	// setup and starts the command
-	cmd := exec.Command(r.ShellCommand[0], r.ShellCommand[1:]...)
-	out := io.MultiWriter(append(r.additionalWriters, r.screen)...)
-	cmd.Stdout = out
-	cmd.Stderr = out
+	cmd := r.newCommand(r.ShellCommand)
+	screen, flooded := r.budgetedOutput(r.screen)
+	out, flush := r.maskedOutput(screen)
+	flushInput, err := r.setInput(cmd)
+	if err != nil {
+		return errors.WithStack(err)
+	}
 
 	// starts the given command
-	err := cmd.Start()
+	wait, err := r.startCommand(cmd, out, flooded)
+	r.startedAt = r.clock.Now()
 	if err != nil {
 		var cutOffErr error
 		// write the failure to screen sending to checks
-		_, cutOffErr = r.screen.Write([]byte(fmt.Sprintf("error starting command %s: %v", strings.Join(r.ShellCommand, " "), err)))
+		_, cutOffErr = r.screen.Write([]byte(r.masker.mask(fmt.Sprintf("error starting command %s: %v", strings.Join(r.ShellCommand, " "), err))))
 		if cutOffErr != nil {
 			return errors.Wrapf(cutOffErr, "Error writing update to command")
 		}
 		// fail the check run on application failure
+		r.setFinished(exitStatusNotStarted, -1)
 		cutOffErr = r.createCheckRun(checksConclusionFailure)
 		if cutOffErr != nil {
 			return errors.WithStack(cutOffErr)
@@ -106,9 +416,43 @@ func (r *Run) run() error {
 
 	err = r.createCheckRun("")
 	if err != nil {
+		// nothing monitors the command, it must not be left running
+		_ = signalGroup(cmd.Process.Pid, syscall.SIGKILL)
+		_ = wait()
+		_ = flush()
+		_ = flushInput()
 		return errors.WithStack(err)
 	}
 
+	return errors.WithStack(r.monitor(func() error {
+		err := wait()
+		flushErr := flush()
+		if err == nil {
+			err = flushErr
+		}
+		flushErr = flushInput()
+		if err == nil {
+			err = flushErr
+		}
+		return err
+	}))
+}
+
+// screens returns the screens the output is written to
+func (r *Run) screens() []*SyncScreen {
+	screens := []*SyncScreen{r.screen}
+	if r.inputScreen != nil {
+		screens = append(screens, r.inputScreen)
+	}
+	for _, s := range r.steps {
+		screens = append(screens, s.screen)
+	}
+	return screens
+}
+
+// monitor keeps updating the check run on every tick while execute is running,
+// and sends the last update with the conclusion once it returns
+func (r *Run) monitor(execute func() error) error {
 	// for sending done signal
 	done := make(chan error)
 	// make a cancellable context for the command ticker
@@ -120,33 +464,15 @@ func (r *Run) run() error {
 		close(done)
 	}()
 
-	go func() {
-		// given the behaviour of sub process receiving signals is unpredictable
-		// the best we could do is to keep sending signals to the sub process
-		for s := range r.sigChan {
-			if cmd.ProcessState != nil && cmd.ProcessState.Exited() { // no need to send to exited process
-				return
-			}
-			sigErr := cmd.Process.Signal(s)
-			// if a process has finished and slipped through the ProcessState.Exited above
-			// signal send to it will return os.ErrProcessDon, in this case, just stop sending signals to it
-			if sigErr != nil {
-				if errors.Is(sigErr, os.ErrProcessDone) {
-					return
-				}
-				done <- errors.Wrapf(sigErr, "error sending signals to sub process")
-			}
-		}
-	}()
-
-	waiter := r.clock.TickerFunc(
-		ctx,
-		r.UpdateFrequency,
-		func() error {
-			return errors.WithStack(r.updateCheckRun(""))
-		},
-		"read-command-output",
-	)
+	update := func() error {
+		return errors.WithStack(r.updateCheckRun(""))
+	}
+	var waiter quartz.Waiter
+	if r.AdaptiveUpdates {
+		waiter = r.startAdaptiveUpdates(ctx, update)
+	} else {
+		waiter = r.clock.TickerFunc(ctx, r.UpdateFrequency, update, "read-command-output")
+	}
 
 	// wait for the ticker go routine to complete
 	// this ticker will keep going on until command
@@ -159,19 +485,15 @@ func (r *Run) run() error {
 		}
 	}()
 
-	// wait for the command to finish and notify the done channel
+	// wait for the execution to finish and notify the done channel
 	go func() {
-		err := cmd.Wait()
-		if err != nil {
-			done <- errors.Wrapf(err, "error finishing the command")
-			return
-		}
-		done <- nil
+		done <- execute()
 	}()
 
 	execErr := <-done
+	r.setFinished(exitStatus(execErr), exitCode(execErr))
 	if execErr != nil {
-		err = r.updateCheckRun(checksConclusionFailure)
+		err := r.updateCheckRun(checksConclusionFailure)
 		if err != nil {
 			return errors.Wrapf(err, "error sending last update")
 		}
@@ -179,10 +501,152 @@ func (r *Run) run() error {
 		return errors.WithStack(execErr)
 	}
 
-	err = r.updateCheckRun(checksConclusionSuccess)
+	if used, exceeded := r.maxRSSExceeded(); exceeded {
+		conclusion := r.MaxRSSConclusion
+		if conclusion == "" {
+			conclusion = checksConclusionFailure
+		}
+		err := r.updateCheckRun(conclusion)
+		if err != nil {
+			return errors.Wrapf(err, "error sending last update")
+		}
+
+		if conclusion == checksConclusionFailure {
+			return errors.Errorf("max RSS of %s exceeded the limit of %s", formatBytes(used), formatBytes(r.maxRSS))
+		}
+		return nil
+	}
+
+	err := r.updateCheckRun(checksConclusionSuccess)
 	if err != nil {
 		return errors.Wrapf(err, "error sending last update")
 	}
 
 	return nil
 }
+
+// setFinished records the end of the execution with its exit status and code
+func (r *Run) setFinished(status string, code int) {
+	r.resultLock.Lock()
+	defer r.resultLock.Unlock()
+	r.finishedAt = r.clock.Now()
+	r.exitStatus = status
+	r.exitCode = code
+}
+
+// setConclusion records the conclusion the check run was completed with
+func (r *Run) setConclusion(conclusion string) {
+	r.resultLock.Lock()
+	defer r.resultLock.Unlock()
+	r.conclusion = conclusion
+}
+
+// exitCode returns the exit code of the execution from the error it returned, -1 when it did not exit
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return -1
+	}
+
+	return exitErr.ExitCode()
+}
+
+// exitStatus describes how the execution ended from the error it returned
+func exitStatus(err error) string {
+	if err == nil {
+		return "0"
+	}
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return "error"
+	}
+
+	if exitErr.ExitCode() >= 0 {
+		return strconv.Itoa(exitErr.ExitCode())
+	}
+
+	// killed by signal
+	return exitErr.String()
+}
+
+// wait forwards the signals received to the started command until it finishes, killing it once flooded is closed
+func (r *Run) wait(cmd *exec.Cmd, flooded <-chan struct{}) error {
+	stop := make(chan struct{})
+	defer close(stop)
+
+	// closed once the command exited, the signals are not sent any more
+	exited := make(chan struct{})
+	waitErr := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		close(exited)
+		waitErr <- err
+	}()
+
+	sigErr := make(chan error, 1)
+	killed := make(chan struct{}, 1)
+	policy := r.signalPolicy
+	if policy == nil {
+		policy = defaultSignalPolicy
+	}
+	go func() {
+		received := 0
+		// given the behaviour of sub process receiving signals is unpredictable
+		// the best we could do is to keep sending signals to the sub process
+		for {
+			select {
+			case s := <-r.sigChan:
+				if !policy.forwards(s) {
+					continue
+				}
+				received++
+				s = policy.apply(s, received)
+				select {
+				case <-exited: // no need to send to exited process
+					return
+				default:
+				}
+				// the whole group, so that the processes started by the command get the signal as well
+				err := signalGroup(cmd.Process.Pid, s)
+				// if the group has finished and slipped through the exited check above
+				// signal send to it will return os.ErrProcessDone, in this case, just stop sending signals to it
+				if err != nil {
+					if !errors.Is(err, os.ErrProcessDone) {
+						sigErr <- errors.Wrapf(err, "error sending signals to sub process")
+					}
+					return
+				}
+			case <-flooded:
+				killed <- struct{}{}
+				flooded = nil
+				err := signalGroup(cmd.Process.Pid, syscall.SIGKILL)
+				if err != nil && !errors.Is(err, os.ErrProcessDone) {
+					sigErr <- errors.Wrapf(err, "error killing sub process")
+					return
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	select {
+	case err := <-sigErr:
+		return errors.WithStack(err)
+	case err := <-waitErr:
+		select {
+		case <-killed:
+			return errors.Wrapf(err, "output of the command exceeded its limit")
+		default:
+		}
+		if err != nil {
+			return errors.Wrapf(err, "error finishing the command")
+		}
+		return nil
+	}
+}
@@ -2,13 +2,17 @@ package run
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"github.com/coder/quartz"
 	"github.com/google/go-github/v64/github"
 	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
-	"sync"
 	"syscall"
 	"testing"
 	"time"
@@ -32,65 +36,42 @@ type runConfig struct {
 	frequency time.Duration
 }
 
-func newInMemoryChecksService(t *testing.T, runId int64) *inMemoryChecksService {
-	return &inMemoryChecksService{
-		CheckRuns: make([]*wrappedCheckRun, 0),
-		RunId:     runId,
-		lock:      &sync.Mutex{},
-		t:         t,
-	}
-}
-
-type inMemoryChecksService struct {
-	CheckRuns []*wrappedCheckRun
-	RunId     int64
-	lock      *sync.Mutex
-	t         *testing.T
+// newMockChecksService returns a mock checks service giving the ID to the next check run created
+func newMockChecksService(clock quartz.Clock, nextID int64) *MockChecksService {
+	s := NewMockChecksService(clock)
+	s.lastID = nextID - 1
+	return s
 }
 
-func (i *inMemoryChecksService) CreateCheckRun(ctx context.Context, owner, repo string, opts github.CreateCheckRunOptions) (*github.CheckRun, *github.Response, error) {
-	i.t.Helper()
-	i.lock.Lock()
-	defer i.lock.Unlock()
-	i.CheckRuns = append(i.CheckRuns, &wrappedCheckRun{
-		Owner:    owner,
-		Repo:     repo,
-		RunId:    i.RunId,
-		CheckRun: opts,
-	})
-	return &github.CheckRun{ID: github.Int64(i.RunId)}, nil, nil
-}
-
-func (i *inMemoryChecksService) UpdateCheckRun(ctx context.Context, owner, repo string, checkRunID int64, opts github.UpdateCheckRunOptions) (*github.CheckRun, *github.Response, error) {
-	i.t.Helper()
-	i.lock.Lock()
-	defer i.lock.Unlock()
-	i.CheckRuns = append(i.CheckRuns, &wrappedCheckRun{
-		Owner:    owner,
-		Repo:     repo,
-		RunId:    checkRunID,
-		CheckRun: opts,
+// addCheckRun adds a check run of the sample commit to the mock checks service, as if created beforehand
+func addCheckRun(s *MockChecksService, id int64, externalID string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.checkRuns = append(s.checkRuns, &mockCheckRun{
+		Owner:      sampleOwner,
+		Repository: sampleRepo,
+		CheckRun: &github.CheckRun{
+			ID:         github.Int64(id),
+			Name:       github.String(sampleName),
+			HeadSHA:    github.String(sampleHeadShA),
+			ExternalID: github.String(externalID),
+			Status:     github.String(checksStatusQueued),
+			Output:     &github.CheckRunOutput{AnnotationsCount: github.Int(0)},
+		},
 	})
-	return nil, nil, nil
 }
 
-func (i *inMemoryChecksService) GetCheckRuns() []wrappedCheckRun {
-	i.t.Helper()
-	i.lock.Lock()
-	defer i.lock.Unlock()
-	out := make([]wrappedCheckRun, len(i.CheckRuns))
-	for i, r := range i.CheckRuns {
-		out[i] = *r
-	}
-
-	return out
+// newSampleRun returns a run of the command reporting to the sample check run, updated every 5 seconds
+func newSampleRun(t *testing.T, args ...string) (*Run, *quartz.Mock) {
+	t.Helper()
+	return newRun(t, &runConfig{runId: 23, frequency: 5 * time.Second}, args...)
 }
 
-type wrappedCheckRun struct {
-	Owner    string
-	Repo     string
-	RunId    int64
-	CheckRun interface{}
+// lastUpdate returns the last update sent to the check run, the one completing it once the run finished
+func lastUpdate(t *testing.T, r *Run) github.UpdateCheckRunOptions {
+	t.Helper()
+	runs := getCheckServiceOutFromRun(t, r).Calls()
+	return runs[len(runs)-1].Options.(github.UpdateCheckRunOptions)
 }
 
 func newRun(t *testing.T, cfg *runConfig, args ...string) (*Run, *quartz.Mock) {
@@ -123,16 +104,26 @@ func newRun(t *testing.T, cfg *runConfig, args ...string) (*Run, *quartz.Mock) {
 		ShellCommand:    args,
 		screen:          screen,
 		clock:           clock,
-		checksService:   newInMemoryChecksService(t, cfg.runId),
+		checksService:   newMockChecksService(clock, cfg.runId),
 		isAuthenticated: true,
 		SyntaxHighlight: highlight,
 		sigChan:         make(chan os.Signal, 1),
+		// the resources used vary from one run to another
+		NoResourceUsage: true,
 	}, clock
 }
 
-func getCheckServiceOutFromRun(t *testing.T, r *Run) *inMemoryChecksService {
+// waitForOutput waits for the screen to show the text, the mock commands print a line once they are ready
+func waitForOutput(t *testing.T, screen *SyncScreen, text string) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		return strings.Contains(screen.ReadScreen(), text)
+	}, 10*time.Second, 5*time.Millisecond)
+}
+
+func getCheckServiceOutFromRun(t *testing.T, r *Run) *MockChecksService {
 	t.Helper()
-	return r.checksService.(*inMemoryChecksService)
+	return r.checksService.(*MockChecksService)
 }
 
 func startCommand(t *testing.T, run *Run, done chan error) {
@@ -175,27 +166,46 @@ type checkRun struct {
 	runId       int64
 	text        string
 	conclusion  string
+	exitStatus  string
 	summary     *string
 	images      []*github.CheckRunImage
 	annotations []*github.CheckRunAnnotation
 	clock       quartz.Clock
+	startedAt   time.Time
 }
 
-func getCreateCheckRunOpt(t *testing.T, cr *checkRun) wrappedCheckRun {
+// getSummary returns the summary expected, with the execution footer once the check run concluded
+func getSummary(t *testing.T, cr *checkRun) string {
 	t.Helper()
 	s := sampleSummary
 	if cr.summary != nil {
 		s = *cr.summary
 	}
+
+	footer := ""
+	if cr.conclusion != "" {
+		status := cr.exitStatus
+		if status == "" {
+			status = "0"
+		}
+		footer = fmt.Sprintf(executionFooterFormat, formatDuration(cr.clock.Now().Sub(cr.startedAt)), status, hostname())
+	}
+
+	return processSummaryWithFooter(s, footer, summaryLimit)
+}
+
+func getCreateCheckRunOpt(t *testing.T, cr *checkRun) MockCall {
+	t.Helper()
 	run := github.CreateCheckRunOptions{
 		Name:       sampleName,
 		HeadSHA:    sampleHeadShA,
 		DetailsURL: github.String(sampleDetailsUrl),
 		ExternalID: github.String(sampleExternalID),
 		Status:     github.String(checksStatusInProgress),
+		StartedAt:  &github.Timestamp{Time: cr.startedAt},
 		Output: &github.CheckRunOutput{
 			Title:       github.String(sampleTitle),
-			Summary:     github.String(s),
+			Summary:     github.String(getSummary(t, cr)),
 			Images:      cr.images,
 			Annotations: cr.annotations,
 		},
@@ -211,24 +221,20 @@ func getCreateCheckRunOpt(t *testing.T, cr *checkRun) wrappedCheckRun {
 		run.CompletedAt = &github.Timestamp{Time: cr.clock.Now()}
 		run.Status = github.String(checksStatusCompleted)
 	}
-	out := wrappedCheckRun{
-		Owner:    sampleOwner,
-		Repo:     sampleRepo,
-		RunId:    cr.runId,
-		CheckRun: run,
+	out := MockCall{
+		Owner:      sampleOwner,
+		Repository: sampleRepo,
+		CheckRunID: cr.runId,
+		Options:    run,
 	}
 	return out
 }
 
-func getUpdateCheckRunOpt(t *testing.T, cr *checkRun) wrappedCheckRun {
+func getUpdateCheckRunOpt(t *testing.T, cr *checkRun) MockCall {
 	t.Helper()
-	s := sampleSummary
-	if cr.summary != nil {
-		s = *cr.summary
-	}
 	output := &github.CheckRunOutput{
 		Title:   github.String(sampleTitle),
-		Summary: github.String(s),
+		Summary: github.String(getSummary(t, cr)),
 	}
 	if cr.text != "" {
 		output.Text = github.String(processOutput(cr.text, highlight))
@@ -247,11 +253,11 @@ func getUpdateCheckRunOpt(t *testing.T, cr *checkRun) wrappedCheckRun {
 		run.CompletedAt = &github.Timestamp{Time: cr.clock.Now()}
 		run.Status = github.String(checksStatusCompleted)
 	}
-	out := wrappedCheckRun{
-		Owner:    sampleOwner,
-		Repo:     sampleRepo,
-		RunId:    cr.runId,
-		CheckRun: run,
+	out := MockCall{
+		Owner:      sampleOwner,
+		Repository: sampleRepo,
+		CheckRunID: cr.runId,
+		Options:    run,
 	}
 
 	return out
@@ -262,6 +268,7 @@ func TestRunEchoCommand(t *testing.T) {
 	echoText := "testing echo"
 	r, clock, done := setupRunAndStart(t, &runConfig{runId: 1, frequency: 5 * time.Second}, false, false, "echo", echoText)
 	defer close(done)
+	start := clock.Now()
 
 	_, wait := clock.AdvanceNext()
 	wait.MustWait(context.Background())
@@ -272,19 +279,21 @@ func TestRunEchoCommand(t *testing.T) {
 		text:       "",
 		conclusion: "",
 		clock:      clock,
+		startedAt:  start,
 	}
 	endCheck := &checkRun{
 		runId:      1,
 		text:       echoText,
 		conclusion: checksConclusionSuccess,
 		clock:      clock,
+		startedAt:  start,
 	}
-	a := []wrappedCheckRun{
+	a := []MockCall{
 		getCreateCheckRunOpt(t, chk),
 		getUpdateCheckRunOpt(t, chk),
 		getUpdateCheckRunOpt(t, endCheck),
 	}
-	b := getCheckServiceOutFromRun(t, r).GetCheckRuns()
+	b := getCheckServiceOutFromRun(t, r).Calls()
 	require.Equal(t, a, b)
 }
 
@@ -293,6 +302,7 @@ func TestCommandOutputShouldRespectEscapeSequenceForControlCharacters(t *testing
 	echoText := "starting\n\033[1A\033[K\nstuff\bff\bs"
 	r, clock, done := setupRunAndStart(t, &runConfig{runId: 2, frequency: 5 * time.Second}, false, false, "echo", echoText)
 	defer close(done)
+	start := clock.Now()
 
 	_, wait := clock.AdvanceNext()
 	wait.MustWait(context.Background())
@@ -303,19 +313,21 @@ func TestCommandOutputShouldRespectEscapeSequenceForControlCharacters(t *testing
 		text:       "",
 		conclusion: "",
 		clock:      clock,
+		startedAt:  start,
 	}
 	endCheck := &checkRun{
 		runId:      2,
 		text:       "\nstuffs",
 		conclusion: checksConclusionSuccess,
 		clock:      clock,
+		startedAt:  start,
 	}
-	a := []wrappedCheckRun{
+	a := []MockCall{
 		getCreateCheckRunOpt(t, chk),
 		getUpdateCheckRunOpt(t, chk),
 		getUpdateCheckRunOpt(t, endCheck),
 	}
-	b := getCheckServiceOutFromRun(t, r).GetCheckRuns()
+	b := getCheckServiceOutFromRun(t, r).Calls()
 	require.Equal(t, a, b)
 }
 
@@ -324,6 +336,7 @@ func TestRunPrintWithSleepCommand(t *testing.T) {
 	printTexts := []string{"line 1", "line 2"}
 	r, clock, done := setupRunAndStart(t, &runConfig{runId: 3, frequency: 5 * time.Second}, false, false, "prints", printTexts...)
 	defer close(done)
+	start := clock.Now()
 
 	// To achieve half done
 	for {
@@ -342,31 +355,34 @@ func TestRunPrintWithSleepCommand(t *testing.T) {
 		text:       "",
 		conclusion: "",
 		clock:      clock,
+		startedAt:  start,
 	}
 	updateChk := &checkRun{
 		runId:      3,
 		text:       "line 1",
 		conclusion: "",
 		clock:      clock,
+		startedAt:  start,
 	}
 	endCheck := &checkRun{
 		runId:      3,
 		text:       "line 1\nline 2",
 		conclusion: checksConclusionSuccess,
 		clock:      clock,
+		startedAt:  start,
 	}
-	expected := []wrappedCheckRun{
+	expected := []MockCall{
 		getCreateCheckRunOpt(t, chk),
 		getUpdateCheckRunOpt(t, updateChk),
 	}
-	actual := getCheckServiceOutFromRun(t, r).GetCheckRuns()
+	actual := getCheckServiceOutFromRun(t, r).Calls()
 	err := <-done
 	require.Equal(t, expected, actual)
 	require.NoError(t, err)
-	expected = append(expected, []wrappedCheckRun{
+	expected = append(expected, []MockCall{
 		getUpdateCheckRunOpt(t, endCheck),
 	}...)
-	actual = getCheckServiceOutFromRun(t, r).GetCheckRuns()
+	actual = getCheckServiceOutFromRun(t, r).Calls()
 	require.Equal(t, expected, actual)
 }
 
@@ -375,6 +391,7 @@ func TestRunErrorMakerCommand(t *testing.T) {
 	errorTexts := []string{"126", "error 1", "details"}
 	r, clock, done := setupRunAndStart(t, &runConfig{runId: 4, frequency: 5 * time.Second}, false, false, "errorm", errorTexts...)
 	defer close(done)
+	start := clock.Now()
 	// advance to the first time break
 	_, wait := clock.AdvanceNext()
 	wait.MustWait(context.Background())
@@ -386,19 +403,22 @@ func TestRunErrorMakerCommand(t *testing.T) {
 		text:       "",
 		conclusion: "",
 		clock:      clock,
+		startedAt:  start,
 	}
 	endCheck := &checkRun{
 		runId:      4,
 		text:       "error 1\ndetails",
 		conclusion: checksConclusionFailure,
+		exitStatus: "126",
 		clock:      clock,
+		startedAt:  start,
 	}
-	expected := []wrappedCheckRun{
+	expected := []MockCall{
 		getCreateCheckRunOpt(t, chk),
 		getUpdateCheckRunOpt(t, chk),
 		getUpdateCheckRunOpt(t, endCheck),
 	}
-	actual := getCheckServiceOutFromRun(t, r).GetCheckRuns()
+	actual := getCheckServiceOutFromRun(t, r).Calls()
 	require.Equal(t, expected, actual)
 
 }
@@ -411,6 +431,7 @@ func TestErrorStartingCommand(t *testing.T) {
 		&runConfig{runId: 5, frequency: 5 * time.Second},
 		true, true, "")
 	defer close(done)
+	start := clock.Now()
 	//first should advance and gets the first line
 	err := <-done
 	require.NotNil(t, err)
@@ -418,12 +439,14 @@ func TestErrorStartingCommand(t *testing.T) {
 		runId:      5,
 		text:       "error starting command : exec: no command",
 		conclusion: checksConclusionFailure,
+		exitStatus: exitStatusNotStarted,
 		clock:      clock,
+		startedAt:  start,
 	}
-	expected := []wrappedCheckRun{
+	expected := []MockCall{
 		getCreateCheckRunOpt(t, endCheck),
 	}
-	actual := getCheckServiceOutFromRun(t, r).GetCheckRuns()
+	actual := getCheckServiceOutFromRun(t, r).Calls()
 	require.Equal(t, expected, actual)
 }
 
@@ -437,6 +460,7 @@ func TestSummaryInFile(t *testing.T) {
 	summaryStr := "summary content"
 	r, clock, done := setupRunAndStart(t, &runConfig{runId: 6, frequency: 5 * time.Second, Summary: name}, false, false, "summary-f", f.Name(), summaryStr, "stdout")
 	defer close(done)
+	start := clock.Now()
 
 	err = <-done
 	require.NoError(t, err)
@@ -447,6 +471,7 @@ func TestSummaryInFile(t *testing.T) {
 		conclusion: "",
 		summary:    &emptyStr,
 		clock:      clock,
+		startedAt:  start,
 	}
 	endCheck := &checkRun{
 		runId:      6,
@@ -454,13 +479,14 @@ func TestSummaryInFile(t *testing.T) {
 		conclusion: checksConclusionSuccess,
 		summary:    &summaryStr,
 		clock:      clock,
+		startedAt:  start,
 	}
-	a := []wrappedCheckRun{
+	a := []MockCall{
 		getCreateCheckRunOpt(t, chk),
 		//getUpdateCheckRunOpt(t, chk),
 		getUpdateCheckRunOpt(t, endCheck),
 	}
-	b := getCheckServiceOutFromRun(t, r).GetCheckRuns()
+	b := getCheckServiceOutFromRun(t, r).Calls()
 	require.Equal(t, a, b)
 }
 
@@ -482,6 +508,7 @@ func TestImageLoading(t *testing.T) {
 			"b", url1,
 		}...)
 	defer close(done)
+	start := clock.Now()
 
 	err = <-done
 	require.NoError(t, err)
@@ -490,12 +517,14 @@ func TestImageLoading(t *testing.T) {
 		text:       "",
 		conclusion: "",
 		clock:      clock,
+		startedAt:  start,
 	}
 	endCheck := &checkRun{
 		runId:      7,
 		text:       "",
 		conclusion: checksConclusionSuccess,
 		clock:      clock,
+		startedAt:  start,
 		images: []*github.CheckRunImage{
 			{
 				ImageURL: github.String(url1),
@@ -505,11 +534,11 @@ func TestImageLoading(t *testing.T) {
 			},
 		},
 	}
-	a := []wrappedCheckRun{
+	a := []MockCall{
 		getCreateCheckRunOpt(t, chk),
 		getUpdateCheckRunOpt(t, endCheck),
 	}
-	b := getCheckServiceOutFromRun(t, r).GetCheckRuns()
+	b := getCheckServiceOutFromRun(t, r).Calls()
 	require.Equal(t, a, b)
 }
 func TestAnnotationLoading(t *testing.T) {
@@ -532,6 +561,7 @@ func TestAnnotationLoading(t *testing.T) {
 			"b", path1,
 		}...)
 	defer close(done)
+	start := clock.Now()
 
 	err = <-done
 	require.NoError(t, err)
@@ -540,23 +570,25 @@ func TestAnnotationLoading(t *testing.T) {
 		text:       "",
 		conclusion: "",
 		clock:      clock,
+		startedAt:  start,
 	}
 	endCheck := &checkRun{
 		runId:      8,
 		text:       "",
 		conclusion: checksConclusionSuccess,
 		clock:      clock,
+		startedAt:  start,
 		annotations: []*github.CheckRunAnnotation{
 			{Path: github.String(path1)},
 			{Path: github.String(path2)},
 			{Path: github.String(path3)},
 		},
 	}
-	a := []wrappedCheckRun{
+	a := []MockCall{
 		getCreateCheckRunOpt(t, chk),
 		getUpdateCheckRunOpt(t, endCheck),
 	}
-	b := getCheckServiceOutFromRun(t, r).GetCheckRuns()
+	b := getCheckServiceOutFromRun(t, r).Calls()
 	require.Equal(t, a, b)
 }
 
@@ -564,6 +596,7 @@ func TestTruncatedOutput(t *testing.T) {
 	t.Parallel()
 	r, clock, done := setupRunAndStart(t, &runConfig{runId: 9, frequency: 5 * time.Second}, false, false, "cat-big-uni")
 	defer close(done)
+	start := clock.Now()
 
 	_, wait := clock.AdvanceNext()
 	wait.MustWait(context.Background())
@@ -574,22 +607,24 @@ func TestTruncatedOutput(t *testing.T) {
 		text:       "",
 		conclusion: "",
 		clock:      clock,
+		startedAt:  start,
 	}
 	endCheck := &checkRun{
 		runId:      9,
 		text:       r.screen.ReadScreen(),
 		conclusion: checksConclusionSuccess,
 		clock:      clock,
+		startedAt:  start,
 	}
-	a := []wrappedCheckRun{
+	a := []MockCall{
 		getCreateCheckRunOpt(t, chk),
 		getUpdateCheckRunOpt(t, chk),
 		getUpdateCheckRunOpt(t, endCheck),
 	}
-	b := getCheckServiceOutFromRun(t, r).GetCheckRuns()
+	b := getCheckServiceOutFromRun(t, r).Calls()
 	require.Equal(t, a, b)
 
-	run := (b[len(b)-1].CheckRun).(github.UpdateCheckRunOptions)
+	run := (b[len(b)-1].Options).(github.UpdateCheckRunOptions)
 	outputText := run.GetOutput().GetText()
 	require.LessOrEqual(t, len(outputText), 65535)
 	truncatePrefix := "```bash\n[truncated]...\n\n"
@@ -613,9 +648,10 @@ func TestTruncatedSummary(t *testing.T) {
 	t.Parallel()
 	textToRepeat := "summary\n"
 	emptySummary := ""
-	summary := processSummary(strings.Repeat(textToRepeat, 8192))
+	summary := strings.Repeat(textToRepeat, 8192)
 	r, clock, done := setupRunAndStart(t, &runConfig{runId: 10, frequency: 5 * time.Second, Summary: name}, false, false, "repeat-summary", name, "8192", textToRepeat)
 	defer close(done)
+	start := clock.Now()
 
 	_, wait := clock.AdvanceNext()
 	wait.MustWait(context.Background())
@@ -627,6 +663,7 @@ func TestTruncatedSummary(t *testing.T) {
 		text:       "",
 		conclusion: "",
 		clock:      clock,
+		startedAt:  start,
 	}
 	endCheck := &checkRun{
 		runId:      10,
@@ -634,16 +671,17 @@ func TestTruncatedSummary(t *testing.T) {
 		text:       r.screen.ReadScreen(),
 		conclusion: checksConclusionSuccess,
 		clock:      clock,
+		startedAt:  start,
 	}
-	a := []wrappedCheckRun{
+	a := []MockCall{
 		getCreateCheckRunOpt(t, chk),
 		getUpdateCheckRunOpt(t, chk),
 		getUpdateCheckRunOpt(t, endCheck),
 	}
-	b := getCheckServiceOutFromRun(t, r).GetCheckRuns()
+	b := getCheckServiceOutFromRun(t, r).Calls()
 	require.Equal(t, a, b)
 
-	run := (b[len(b)-1].CheckRun).(github.UpdateCheckRunOptions)
+	run := (b[len(b)-1].Options).(github.UpdateCheckRunOptions)
 	outputSummary := run.GetOutput().GetSummary()
 	require.LessOrEqual(t, len(outputSummary), 65535)
 	require.True(t, strings.HasPrefix(outputSummary, truncatedTextReplacement))
@@ -654,13 +692,12 @@ func TestRunCaptureSignalCommand(t *testing.T) {
 	t.Parallel()
 	r, clock, done := setupRunAndStart(t, &runConfig{runId: 1, frequency: 5 * time.Second}, false, false, "capture-signal")
 	defer close(done)
+	start := clock.Now()
 
+	waitForOutput(t, r.screen, "ready")
 	_, wait := clock.AdvanceNext()
 	wait.MustWait(context.Background())
-	go func() {
-		time.Sleep(100 * time.Millisecond)
-		r.sigChan <- syscall.SIGINT
-	}()
+	r.sigChan <- syscall.SIGINT
 	err := <-done
 	require.Error(t, err)
 	chk := &checkRun{
@@ -668,18 +705,238 @@ func TestRunCaptureSignalCommand(t *testing.T) {
 		text:       "",
 		conclusion: "",
 		clock:      clock,
+		startedAt:  start,
+	}
+	readyCheck := &checkRun{
+		runId:      1,
+		text:       "ready",
+		conclusion: "",
+		clock:      clock,
+		startedAt:  start,
 	}
 	endCheck := &checkRun{
 		runId:      1,
-		text:       "capture signal: interrupt",
+		text:       "ready\ncapture signal: interrupt",
 		conclusion: checksConclusionFailure,
+		exitStatus: "1",
 		clock:      clock,
+		startedAt:  start,
 	}
-	a := []wrappedCheckRun{
+	a := []MockCall{
 		getCreateCheckRunOpt(t, chk),
-		getUpdateCheckRunOpt(t, chk),
+		getUpdateCheckRunOpt(t, readyCheck),
 		getUpdateCheckRunOpt(t, endCheck),
 	}
-	b := getCheckServiceOutFromRun(t, r).GetCheckRuns()
+	b := getCheckServiceOutFromRun(t, r).Calls()
 	require.Equal(t, a, b)
 }
+
+func TestActions(t *testing.T) {
+	t.Parallel()
+	f := filepath.Join(t.TempDir(), "actions.json")
+	require.NoError(t, os.WriteFile(f, []byte(`[{"label":"Update snapshots","description":"Regenerate the snapshots","identifier":"snapshots"}]`), 0644))
+
+	r := &Run{
+		Action:      []string{"debug:Re-run with debug:Runs again with debug: on"},
+		ActionsFile: f,
+	}
+	actions, err := r.getActions()
+	require.NoError(t, err)
+	require.Equal(t, []*github.CheckRunAction{
+		{Identifier: "debug", Label: "Re-run with debug", Description: "Runs again with debug: on"},
+		{Identifier: "snapshots", Label: "Update snapshots", Description: "Regenerate the snapshots"},
+	}, actions)
+
+	r.Action = []string{"a:a:a", "b:b:b", "c:c:c"}
+	_, err = r.getActions()
+	require.ErrorContains(t, err, "at most 3 actions")
+
+	r = &Run{Action: []string{"debug:this label is way too long:description"}}
+	_, err = r.getActions()
+	require.ErrorContains(t, err, "longer than 20 characters")
+
+	r = &Run{Action: []string{"debug"}}
+	_, err = r.getActions()
+	require.ErrorContains(t, err, "identifier:label:description")
+}
+
+func TestInvalidActionsStartNothing(t *testing.T) {
+	t.Parallel()
+	marker := filepath.Join(t.TempDir(), "marker")
+	r, _ := newSampleRun(t, "sh", "-c", "echo ran > "+marker)
+	r.Action = []string{"bad"}
+
+	require.ErrorContains(t, r.Run(&Config{}), "error getting actions")
+	require.NoFileExists(t, marker)
+	require.Empty(t, getCheckServiceOutFromRun(t, r).Calls())
+}
+
+func TestAttachToCheckRunByID(t *testing.T) {
+	t.Parallel()
+	r, _ := newRun(t, &runConfig{runId: 15, frequency: 5 * time.Second}, command(t, "echo", "attached")...)
+	r.CheckRunID = 77
+	addCheckRun(getCheckServiceOutFromRun(t, r), 77, sampleExternalID)
+
+	require.NoError(t, r.Run(&Config{}))
+	runs := getCheckServiceOutFromRun(t, r).Calls()
+	for _, run := range runs {
+		require.IsType(t, github.UpdateCheckRunOptions{}, run.Options)
+		require.Equal(t, int64(77), run.CheckRunID)
+	}
+	update := runs[len(runs)-1].Options.(github.UpdateCheckRunOptions)
+	require.Equal(t, checksConclusionSuccess, update.GetConclusion())
+}
+
+func TestAttachToCheckRunByExternalID(t *testing.T) {
+	t.Parallel()
+	r, _ := newRun(t, &runConfig{runId: 16, frequency: 5 * time.Second}, command(t, "echo", "attached")...)
+	r.ReuseByExternalID = true
+	service := getCheckServiceOutFromRun(t, r)
+	addCheckRun(service, 87, "other")
+	addCheckRun(service, 88, sampleExternalID)
+
+	require.NoError(t, r.Run(&Config{}))
+	runs := service.Calls()
+	require.IsType(t, github.UpdateCheckRunOptions{}, runs[0].Options)
+	require.Equal(t, int64(88), runs[0].CheckRunID)
+}
+
+func TestAttachToCheckRunByExternalIDCreatesWhenMissing(t *testing.T) {
+	t.Parallel()
+	r, _ := newRun(t, &runConfig{runId: 17, frequency: 5 * time.Second}, command(t, "echo", "attached")...)
+	r.ReuseByExternalID = true
+	service := getCheckServiceOutFromRun(t, r)
+	addCheckRun(service, 87, "other")
+
+	require.NoError(t, r.Run(&Config{}))
+	runs := service.Calls()
+	require.IsType(t, github.CreateCheckRunOptions{}, runs[0].Options)
+	require.Equal(t, int64(17), runs[len(runs)-1].CheckRunID)
+}
+
+func TestCreateQueued(t *testing.T) {
+	t.Parallel()
+	r, clock := newRun(t, &runConfig{runId: 20, frequency: 5 * time.Second}, command(t, "echo", "queued")...)
+	r.CreateQueued = true
+
+	require.NoError(t, r.Run(&Config{}))
+	runs := getCheckServiceOutFromRun(t, r).Calls()
+
+	created := runs[0].Options.(github.CreateCheckRunOptions)
+	require.Equal(t, checksStatusQueued, created.GetStatus())
+	require.Nil(t, runs[0].StartedAt)
+
+	started := runs[1].Options.(github.UpdateCheckRunOptions)
+	require.Equal(t, checksStatusInProgress, started.GetStatus())
+	require.Equal(t, int64(20), runs[1].CheckRunID)
+	require.Equal(t, clock.Now(), *runs[1].StartedAt)
+
+	for _, run := range runs[2:] {
+		require.Nil(t, run.StartedAt)
+	}
+	completed := runs[len(runs)-1].Options.(github.UpdateCheckRunOptions)
+	require.Equal(t, checksConclusionSuccess, completed.GetConclusion())
+}
+
+func TestQueue(t *testing.T) {
+	t.Parallel()
+	clock := quartz.NewMock(t)
+	stdout := &strings.Builder{}
+	q := &Queue{
+		Owner:           sampleOwner,
+		Repository:      sampleRepo,
+		CommitSHA:       sampleHeadShA,
+		Names:           []string{"lint", "test"},
+		clock:           clock,
+		stdout:          stdout,
+		checksService:   newMockChecksService(clock, 21),
+		isAuthenticated: true,
+	}
+
+	require.NoError(t, q.Run())
+	require.Equal(t, "lint\t21\ntest\t22\n", stdout.String())
+	runs := q.checksService.(*MockChecksService).Calls()
+	require.Len(t, runs, 2)
+	for i, name := range q.Names {
+		created := runs[i].Options.(github.CreateCheckRunOptions)
+		require.Equal(t, name, created.Name)
+		require.Equal(t, checksStatusQueued, created.GetStatus())
+		require.Equal(t, name, created.GetOutput().GetTitle())
+	}
+}
+
+func TestQueueMasksSummary(t *testing.T) {
+	t.Parallel()
+	clock := quartz.NewMock(t)
+	q := &Queue{
+		Owner:           sampleOwner,
+		Repository:      sampleRepo,
+		CommitSHA:       sampleHeadShA,
+		Manifest:        writeManifest(t, []*MultiCheck{{Name: "deploy", Summary: "deploying with hidden-value", Command: []string{"deploy"}}}),
+		MaskFlags:       MaskFlags{Mask: []string{"hidden-value"}},
+		clock:           clock,
+		stdout:          &strings.Builder{},
+		checksService:   NewMockChecksService(clock),
+		isAuthenticated: true,
+	}
+
+	require.NoError(t, q.Run())
+	runs := q.checksService.(*MockChecksService).Calls()
+	created := runs[0].Options.(github.CreateCheckRunOptions)
+	require.Contains(t, created.GetOutput().GetSummary(), "deploying with ***")
+}
+
+func TestGithubChecksServiceStartCheckRun(t *testing.T) {
+	t.Parallel()
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		require.Equal(t, http.MethodPatch, req.Method)
+		require.Equal(t, "/repos/sampleOwner/sampleRepo/check-runs/22", req.URL.Path)
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+		_, _ = w.Write([]byte(`{"id":22}`))
+	}))
+	defer server.Close()
+
+	client := github.NewClient(server.Client())
+	baseURL, err := url.Parse(server.URL + "/")
+	require.NoError(t, err)
+	client.BaseURL = baseURL
+
+	startedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	service := NewChecksService(client).(checkRunStarter)
+	checkRun, _, err := service.StartCheckRun(context.Background(), sampleOwner, sampleRepo, 22, startedAt, github.UpdateCheckRunOptions{
+		Name:   sampleName,
+		Status: github.String(checksStatusInProgress),
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(22), checkRun.GetID())
+	require.Equal(t, map[string]any{
+		"name":       sampleName,
+		"status":     checksStatusInProgress,
+		"started_at": "2024-01-02T03:04:05Z",
+	}, body)
+}
+
+func TestNoExecutionFooter(t *testing.T) {
+	t.Parallel()
+	r, _ := newSampleRun(t, command(t, "echo", "no footer")...)
+	r.NoExecutionFooter = true
+
+	require.NoError(t, r.Run(&Config{}))
+	completed := lastUpdate(t, r)
+	require.Equal(t, checksConclusionSuccess, completed.GetConclusion())
+	require.Equal(t, sampleSummary, completed.GetOutput().GetSummary())
+}
+
+func TestParsePtySize(t *testing.T) {
+	t.Parallel()
+	cols, rows, err := parsePtySize("120x40")
+	require.NoError(t, err)
+	require.Equal(t, 120, cols)
+	require.Equal(t, 40, rows)
+
+	for _, size := range []string{"120", "0x40", "x", "120x-1", "70000x20"} {
+		_, _, err = parsePtySize(size)
+		require.Error(t, err, size)
+	}
+}
@@ -1,40 +1,316 @@
 package run
 
 import (
+	"bytes"
 	"github.com/buildkite/terminal-to-html/v3"
 	"github.com/pkg/errors"
+	"html"
+	"strings"
 	"sync"
 )
 
+const (
+	// screenQueueLimit is the length of output queued before the writer parses it into the screen itself
+	screenQueueLimit = 256 * 1024
+	// screenWindowLines is the height of the terminal window, the cursor can't move above it so that the lines
+	// scrolling out of it never change again. It is the default of terminal-to-html
+	screenWindowLines = 100
+)
+
+// eraseScrollback is the escape sequence erasing the whole display including the scrollback, e.g. printed by clear
+var eraseScrollback = []byte("\x1b[3J")
+
 // NewSyncScreen initiate a new instance of SyncScreen
 func NewSyncScreen(opts ...terminal.ScreenOption) (*SyncScreen, error) {
+	t := &SyncScreen{
+		Lock:      &sync.RWMutex{},
+		plainLive: true,
+		htmlLive:  true,
+	}
+
+	// the window is bounded so that the lines scrolling out of it are handed over to the frozen lines
+	opts = append(opts, terminal.WithMaxSize(0, screenWindowLines))
 	screen, err := terminal.NewScreen(opts...)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
+	screen.ScrollOutFunc = t.freeze
+	t.Screen = screen
 
-	return &SyncScreen{
-		Screen: screen,
-		Lock:   &sync.RWMutex{},
-	}, nil
+	return t, nil
 }
 
-// SyncScreen wraps the terminal.Screen read write with a RWLock
+// SyncScreen wraps the terminal.Screen read write with a lock. The output written is queued and only parsed into
+// the screen when it is read or once the queue is long, so that the command writing it does not wait for the screen
+// being rendered.
+//
+// Only the lines of the terminal window can change, the lines scrolling out of it are rendered once and kept frozen,
+// so that reading the screen only renders the window again, however long the output
 type SyncScreen struct {
 	Screen *terminal.Screen
 	Lock   *sync.RWMutex
+
+	queueLock sync.Mutex
+	queue     []byte
+	written   int64
+
+	// the lines scrolled out of the window rendered as HTML and plain text, guarded by Lock. A negative limit
+	// keeps none of them
+	frozenHTML  lineRing
+	frozenPlain lineRing
+	frozenLimit int
+
+	// the cached renders of the window, guarded by Lock
+	plainLive bool
+	plain     string
+	htmlLive  bool
+	html      string
 }
 
-// ReadScreen returns the AsPlainText from the wrapped screen
+// freeze keeps the line scrolling out of the window, it is called by the screen with the lock held
+func (t *SyncScreen) freeze(lineHTML string) {
+	if t.frozenLimit < 0 {
+		return
+	}
+
+	t.frozenHTML.push(lineHTML)
+	t.frozenPlain.push(htmlLineAsPlain(lineHTML))
+}
+
+// apply parses the queued output into the screen, it is called with the lock held
+func (t *SyncScreen) apply() {
+	t.queueLock.Lock()
+	queue := t.queue
+	t.queue = nil
+	t.queueLock.Unlock()
+
+	if len(queue) == 0 {
+		return
+	}
+
+	// the scrollback erased by the output takes the frozen lines along
+	if i := bytes.LastIndex(queue, eraseScrollback); i >= 0 {
+		_, _ = t.Screen.Write(queue[:i+len(eraseScrollback)])
+		queue = queue[i+len(eraseScrollback):]
+		t.frozenHTML.reset()
+		t.frozenPlain.reset()
+	}
+	_, _ = t.Screen.Write(queue)
+	t.plainLive = false
+	t.htmlLive = false
+}
+
+// SetScrollback bounds the screen to the given number of lines, the lines scrolling out of it are discarded so
+// that the memory it holds and the cost of reading it stay bounded however long the output. 0 keeps every line
+func (t *SyncScreen) SetScrollback(lines int) error {
+	t.Lock.Lock()
+	defer t.Lock.Unlock()
+	t.apply()
+
+	window := screenWindowLines
+	switch {
+	case lines <= 0:
+		t.frozenLimit = 0
+	case lines <= screenWindowLines:
+		// the window holds every line kept
+		window = lines
+		t.frozenLimit = -1
+		t.frozenHTML.reset()
+		t.frozenPlain.reset()
+	default:
+		t.frozenLimit = lines - screenWindowLines
+	}
+	t.frozenHTML.setLimit(max(t.frozenLimit, 0))
+	t.frozenPlain.setLimit(max(t.frozenLimit, 0))
+
+	return errors.WithStack(terminal.WithMaxSize(0, window)(t.Screen))
+}
+
+// renderWindow renders the lines of the window again if they changed, it is called with the lock held
+func (t *SyncScreen) renderWindow() string {
+	t.apply()
+	if !t.plainLive {
+		t.plain = t.Screen.AsPlainText()
+		t.plainLive = true
+	}
+	return t.plain
+}
+
+// ReadScreen returns the plain text of the lines kept by the screen
 func (t *SyncScreen) ReadScreen() string {
-	t.Lock.RLock()
-	defer t.Lock.RUnlock()
-	return t.Screen.AsPlainText()
+	t.Lock.Lock()
+	defer t.Lock.Unlock()
+	window := t.renderWindow()
+	return joinLines(&t.frozenPlain, t.frozenPlain.len(), window)
 }
 
-// Write writes the given bytes into the screen
-func (t *SyncScreen) Write(p []byte) (n int, err error) {
+// ReadTail returns at most the last n bytes of the plain text of the screen, which may start in the middle of a
+// character. Only the frozen lines it ends with are copied so that its cost depends on n rather than on the length
+// of the output
+func (t *SyncScreen) ReadTail(n int) string {
+	if n <= 0 {
+		return ""
+	}
+
+	t.Lock.Lock()
+	defer t.Lock.Unlock()
+	window := t.renderWindow()
+
+	size := len(window)
+	count := 0
+	for i := t.frozenPlain.len() - 1; i >= 0 && size < n; i-- {
+		size += len(t.frozenPlain.at(i)) + 1
+		count++
+	}
+
+	text := joinLines(&t.frozenPlain, count, window)
+	if len(text) > n {
+		text = text[len(text)-n:]
+	}
+	return text
+}
+
+// ReadHTML returns the HTML of the lines kept by the screen
+func (t *SyncScreen) ReadHTML() string {
 	t.Lock.Lock()
 	defer t.Lock.Unlock()
-	return t.Screen.Write(p)
+	t.apply()
+	if !t.htmlLive {
+		t.html = t.Screen.AsHTML()
+		t.htmlLive = true
+	}
+	return joinLines(&t.frozenHTML, t.frozenHTML.len(), t.html)
+}
+
+// Written returns the number of bytes written so far
+func (t *SyncScreen) Written() int64 {
+	t.queueLock.Lock()
+	defer t.queueLock.Unlock()
+	return t.written
+}
+
+// Write queues the given bytes to be written into the screen
+func (t *SyncScreen) Write(p []byte) (n int, err error) {
+	t.queueLock.Lock()
+	t.queue = append(t.queue, p...)
+	t.written += int64(len(p))
+	long := len(t.queue) >= screenQueueLimit
+	t.queueLock.Unlock()
+
+	if long {
+		t.Lock.Lock()
+		defer t.Lock.Unlock()
+		t.apply()
+	}
+	return len(p), nil
+}
+
+// lineRing keeps the last lines pushed into it, up to its limit when positive. Once full, each line overwrites the
+// oldest one so that neither the array nor the lines dropped are held any longer
+type lineRing struct {
+	lines []string
+	start int
+	limit int
+}
+
+// push adds the line after the last one, dropping the oldest line when the ring is full
+func (l *lineRing) push(line string) {
+	if l.limit > 0 && len(l.lines) == l.limit {
+		l.lines[l.start] = line
+		l.start = (l.start + 1) % l.limit
+		return
+	}
+	l.lines = append(l.lines, line)
+}
+
+// len returns the number of lines kept
+func (l *lineRing) len() int {
+	return len(l.lines)
+}
+
+// at returns the i-th line kept, from the oldest
+func (l *lineRing) at(i int) string {
+	return l.lines[(l.start+i)%len(l.lines)]
+}
+
+// reset drops every line
+func (l *lineRing) reset() {
+	l.lines = nil
+	l.start = 0
+}
+
+// setLimit bounds the ring to the given number of lines, 0 keeps every line. The lines kept are copied into a new
+// array so that the lines dropped are released
+func (l *lineRing) setLimit(limit int) {
+	count := l.len()
+	if limit > 0 {
+		count = min(count, limit)
+	}
+
+	lines := make([]string, count)
+	for i := range lines {
+		lines[i] = l.at(l.len() - count + i)
+	}
+	l.lines = lines
+	l.start = 0
+	l.limit = limit
+}
+
+// joinLines joins the last count frozen lines with the rendered window, which is left out when empty
+func joinLines(frozen *lineRing, count int, window string) string {
+	if count == 0 {
+		return window
+	}
+
+	first := frozen.len() - count
+	size := len(window)
+	for i := first; i < frozen.len(); i++ {
+		size += len(frozen.at(i)) + 1
+	}
+
+	builder := strings.Builder{}
+	builder.Grow(size)
+	for i := first; i < frozen.len(); i++ {
+		if i > first {
+			builder.WriteByte('\n')
+		}
+		builder.WriteString(frozen.at(i))
+	}
+	if window != "" {
+		builder.WriteByte('\n')
+		builder.WriteString(window)
+	}
+	return builder.String()
+}
+
+// htmlLineAsPlain turns a line rendered by terminal-to-html back into plain text, dropping its timestamp, styles
+// and images. The line is escaped by the renderer so that its tags are the only angle brackets in it
+func htmlLineAsPlain(line string) string {
+	if line == "&nbsp;" {
+		return ""
+	}
+
+	if strings.HasPrefix(line, "<time ") {
+		if i := strings.Index(line, "</time>"); i >= 0 {
+			line = line[i+len("</time>"):]
+		}
+	}
+
+	builder := strings.Builder{}
+	for line != "" {
+		start := strings.IndexByte(line, '<')
+		if start < 0 {
+			builder.WriteString(line)
+			break
+		}
+		builder.WriteString(line[:start])
+
+		end := strings.IndexByte(line[start:], '>')
+		if end < 0 {
+			break
+		}
+		line = line[start+end+1:]
+	}
+
+	return strings.TrimRight(html.UnescapeString(builder.String()), " \t")
 }
@@ -0,0 +1,189 @@
+package run
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/buildkite/terminal-to-html/v3"
+	"github.com/coder/quartz"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetScrollback(t *testing.T) {
+	t.Parallel()
+	screen, err := NewSyncScreen()
+	require.NoError(t, err)
+	require.NoError(t, screen.SetScrollback(3))
+
+	for i := 1; i <= 1000; i++ {
+		_, err = fmt.Fprintf(screen, "line %d\n", i)
+		require.NoError(t, err)
+	}
+	require.Equal(t, "line 998\nline 999\nline 1000", screen.ReadScreen())
+}
+
+func TestScrollbackAndOutputFile(t *testing.T) {
+	t.Parallel()
+	file := filepath.Join(t.TempDir(), "output.log")
+	r, _ := newSampleRun(t, "seq", "100")
+	r.Scrollback = 5
+	r.OutputFile = file
+
+	require.NoError(t, r.Run(&Config{}))
+	completed := lastUpdate(t, r)
+	require.Equal(t, "```bash\n96\n97\n98\n99\n100\n```", completed.GetOutput().GetText())
+
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	require.Len(t, lines, 100)
+	require.Equal(t, "1", lines[0])
+}
+
+func TestSyncScreenRendersWindowOnly(t *testing.T) {
+	t.Parallel()
+	screen, err := NewSyncScreen()
+	require.NoError(t, err)
+
+	for i := 1; i <= 150; i++ {
+		_, err = fmt.Fprintf(screen, "line %d\n", i)
+		require.NoError(t, err)
+	}
+	require.True(t, strings.HasPrefix(screen.ReadScreen(), "line 1\nline 2\n"))
+	require.Equal(t, 50, screen.frozenPlain.len())
+	require.True(t, screen.plainLive)
+
+	_, err = screen.Write([]byte("\x1b[2Aupdated"))
+	require.NoError(t, err)
+	require.Equal(t, "updated9\nline 150", screen.ReadTail(len("updated9\nline 150")))
+	require.Equal(t, 50, screen.frozenPlain.len())
+}
+
+func TestLineRing(t *testing.T) {
+	t.Parallel()
+	ring := lineRing{}
+	for i := 1; i <= 5; i++ {
+		ring.push(fmt.Sprint(i))
+	}
+	ring.setLimit(3)
+	require.Equal(t, "3\n4\n5", joinLines(&ring, ring.len(), ""))
+
+	for i := 6; i <= 10; i++ {
+		ring.push(fmt.Sprint(i))
+	}
+	require.Equal(t, 3, cap(ring.lines))
+	require.Equal(t, "9\n10\nwindow", joinLines(&ring, 2, "window"))
+
+	ring.setLimit(0)
+	ring.push("11")
+	require.Equal(t, "8\n9\n10\n11", joinLines(&ring, ring.len(), ""))
+}
+
+func TestSyncScreenMatchesTerminal(t *testing.T) {
+	t.Parallel()
+	var output []byte
+	for i := 0; i < 300; i++ {
+		output = append(output, fmt.Sprintf("\x1b[3%dm%d\x1b[0m <a & 'b'> / \"c\"   \n", i%8, i)...)
+		if i%7 == 0 {
+			output = append(output, "\n  \n"...)
+		}
+		if i%10 == 0 {
+			output = append(output, fmt.Sprintf("progress %d%%\rprogress done\n\x1b]8;;https://example.com\x1b\\link %d\x1b]8;;\x1b\\\n", i, i)...)
+		}
+		if i%50 == 0 {
+			output = append(output, "\x1b[5Arewritten\x1b[5B\n"...)
+		}
+	}
+
+	for _, tc := range []struct {
+		name   string
+		output []byte
+	}{
+		{name: "styles", output: output},
+		{name: "clear", output: append(output[:len(output):len(output)], "\x1b[H\x1b[2J\x1b[3Jcleared\n"...)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			screen, err := NewSyncScreen()
+			require.NoError(t, err)
+			expected, err := terminal.NewScreen()
+			require.NoError(t, err)
+			for _, chunk := range bytes.SplitAfter(tc.output, []byte("\n")) {
+				_, err = screen.Write(chunk)
+				require.NoError(t, err)
+				_, err = expected.Write(chunk)
+				require.NoError(t, err)
+			}
+
+			require.Equal(t, expected.AsPlainText(), screen.ReadScreen())
+			require.Equal(t, expected.AsHTML(), screen.ReadHTML())
+			for _, n := range []int{1, 10, 1000, 1 << 20} {
+				plain := expected.AsPlainText()
+				require.Equal(t, plain[max(len(plain)-n, 0):], screen.ReadTail(n))
+			}
+		})
+	}
+}
+
+// benchmarkLines is a few megabytes of output, in the chunks a command writes it
+var benchmarkLines = func() [][]byte {
+	lines := make([][]byte, 0, 50000)
+	for i := 0; i < 50000; i++ {
+		lines = append(lines, []byte(fmt.Sprintf("\x1b[32m%06d\x1b[0m ok   github.com/block/checks4shell/cmd/run/some/long/package/path 0.%03ds\n", i, i%1000)))
+	}
+	return lines
+}()
+
+// BenchmarkScreenWrite writes multi-megabyte outputs to the screen while the check run is updated
+func BenchmarkScreenWrite(b *testing.B) {
+	for _, scrollback := range []int{0, 10000} {
+		b.Run(fmt.Sprintf("scrollback-%d", scrollback), func(b *testing.B) {
+			size := 0
+			for _, line := range benchmarkLines {
+				size += len(line)
+			}
+			b.SetBytes(int64(size))
+
+			for i := 0; i < b.N; i++ {
+				screen, err := NewSyncScreen()
+				require.NoError(b, err)
+				require.NoError(b, screen.SetScrollback(scrollback))
+				for j, line := range benchmarkLines {
+					_, err = screen.Write(line)
+					require.NoError(b, err)
+					if j%5000 == 0 {
+						processOutputWithLimit(screen.ReadScreen(), "bash", outputLimit)
+					}
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkCheckRunOutput renders the check run output of a multi-megabyte output on every tick, with the output
+// either unchanged between ticks or written to by the command in the meantime
+func BenchmarkCheckRunOutput(b *testing.B) {
+	for _, written := range []int{0, 100} {
+		b.Run(fmt.Sprintf("written-%d", written), func(b *testing.B) {
+			screen, err := NewSyncScreen()
+			require.NoError(b, err)
+			for _, line := range benchmarkLines {
+				_, err = screen.Write(line)
+				require.NoError(b, err)
+			}
+			r := &Run{Title: sampleTitle, Summary: sampleSummary, screen: screen, clock: quartz.NewReal(), SyntaxHighlight: "bash"}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for j := 0; j < written; j++ {
+					_, err = screen.Write(benchmarkLines[(i*written+j)%len(benchmarkLines)])
+					require.NoError(b, err)
+				}
+				_, err = r.getCheckRunOutput("")
+				require.NoError(b, err)
+			}
+		})
+	}
+}
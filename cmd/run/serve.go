@@ -0,0 +1,163 @@
+package run
+
+import (
+	"fmt"
+	"github.com/alecthomas/kong"
+	"github.com/coder/quartz"
+	"github.com/google/go-github/v64/github"
+	"github.com/pkg/errors"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	checksActionRerequested = "rerequested"
+)
+
+// Serve is the struct for the serve command, it receives check_run and check_suite rerequested
+// webhooks and runs the checks again against the same head SHA
+type Serve struct {
+	Listen          string        `short:"L" env:"CHECKS4SHELL_LISTEN" help:"Address to listen on for webhooks" default:":8080"`
+	WebhookSecret   string        `env:"CHECKS4SHELL_WEBHOOK_SECRET" required:"" help:"Secret used to verify the signature of the webhooks"`
+	UpdateFrequency time.Duration `short:"f" env:"CHECKS4SHELL_UPDATE_FREQUENCY" help:"Frequency to update the check runs" default:"5s"`
+	Concurrency     int           `short:"p" env:"CHECKS4SHELL_CONCURRENCY" help:"Maximum number of commands running at the same time for a rerequested check suite" default:"4"`
+	Debug           bool          `short:"d" help:"Enable debug mode"`
+	Manifest        string        `arg:"" type:"existingfile" help:"JSON manifest file mapping the check names to the commands, in the same format as the multi command"`
+
+	clock  quartz.Clock
+	checks []*MultiCheck
+
+	stdout          io.Writer
+	stdoutLock      *sync.Mutex
+	treeLock        *sync.Mutex
+	wg              *sync.WaitGroup
+	checksService   ChecksService
+	isAuthenticated bool
+}
+
+// AfterApply will run on CLI and initialise the missing properties
+func (s *Serve) AfterApply(_ *kong.Context, cfg *Config) error {
+	if s.clock == nil {
+		s.clock = quartz.NewReal()
+	}
+
+	if s.stdout == nil {
+		s.stdout = os.Stdout
+	}
+
+	s.stdoutLock = &sync.Mutex{}
+	s.treeLock = &sync.Mutex{}
+	s.wg = &sync.WaitGroup{}
+	s.checksService = cfg.ChecksService
+	s.isAuthenticated = cfg.IsAuthenticated
+
+	return nil
+}
+
+// Run serves the webhook receiver
+func (s *Serve) Run() error {
+	checks, err := readManifest(s.Manifest)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	s.checks = checks
+
+	return errors.WithStack(serveUntilSignal(s.Listen, s.handler(), s.stdout, s.wg))
+}
+
+func (s *Serve) handler() http.Handler {
+	return &webhookHandler{
+		secret: []byte(s.WebhookSecret),
+		handle: s.handle,
+	}
+}
+
+func (s *Serve) handle(event any) (int, string) {
+	var (
+		repo   *github.Repository
+		sha    string
+		env    []string
+		checks []*MultiCheck
+	)
+
+	switch e := event.(type) {
+	case *github.CheckRunEvent:
+		if e.GetAction() != checksActionRerequested {
+			return http.StatusOK, "ignored"
+		}
+
+		name := e.GetCheckRun().GetName()
+		check := s.findCheck(name)
+		if check == nil {
+			return http.StatusNotFound, fmt.Sprintf("no command for check %s", name)
+		}
+
+		repo, sha, checks = e.GetRepo(), e.GetCheckRun().GetHeadSHA(), []*MultiCheck{check}
+		env = checkRunEnv(repo, e.GetCheckRun())
+	case *github.CheckSuiteEvent:
+		if e.GetAction() != checksActionRerequested {
+			return http.StatusOK, "ignored"
+		}
+
+		repo, sha, checks = e.GetRepo(), e.GetCheckSuite().GetHeadSHA(), s.checks
+		env = commitEnv(repo, sha)
+	default:
+		return http.StatusOK, "ignored"
+	}
+
+	m := s.newMulti(repo, sha, env)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		// every rerun checks out its commit in the same working tree, one rerun at a time
+		s.treeLock.Lock()
+		defer s.treeLock.Unlock()
+		err := m.run(checks)
+		if err != nil {
+			s.log("rerun of %s failed: %v\n", sha, err)
+			return
+		}
+		s.log("rerun of %s finished\n", sha)
+	}()
+
+	return http.StatusAccepted, fmt.Sprintf("rerunning %d check(s) for %s", len(checks), sha)
+}
+
+func (s *Serve) findCheck(name string) *MultiCheck {
+	for _, c := range s.checks {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// newMulti returns a multi command running the checks for the given repository and commit, the commands are run
+// in the working tree of the server with the given environment identifying the commit to check out, the caller
+// holds the working tree lock while they run
+func (s *Serve) newMulti(repo *github.Repository, sha string, env []string) *Multi {
+	return &Multi{
+		Owner:           repo.GetOwner().GetLogin(),
+		Repository:      repo.GetName(),
+		CommitSHA:       sha,
+		UpdateFrequency: s.UpdateFrequency,
+		Concurrency:     s.Concurrency,
+		Debug:           s.Debug,
+		clock:           s.clock,
+		stdout:          s.stdout,
+		stdoutLock:      s.stdoutLock,
+		checksService:   s.checksService,
+		isAuthenticated: s.isAuthenticated,
+		sigChan:         make(chan os.Signal, 1),
+		env:             env,
+	}
+}
+
+func (s *Serve) log(format string, args ...any) {
+	s.stdoutLock.Lock()
+	defer s.stdoutLock.Unlock()
+	_, _ = fmt.Fprintf(s.stdout, format, args...)
+}
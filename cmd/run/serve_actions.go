@@ -0,0 +1,117 @@
+package run
+
+import (
+	"fmt"
+	"github.com/google/go-github/v64/github"
+	"github.com/pkg/errors"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+)
+
+const (
+	checkRunActionRequestedAction = "requested_action"
+)
+
+// ServeActions is the struct for the serve-actions command, it receives check_run.requested_action
+// webhooks and runs the shell command configured for the requested action identifier
+type ServeActions struct {
+	Listen        string            `short:"L" env:"CHECKS4SHELL_LISTEN" help:"Address to listen on for webhooks" default:":8080"`
+	WebhookSecret string            `env:"CHECKS4SHELL_WEBHOOK_SECRET" required:"" help:"Secret used to verify the signature of the webhooks"`
+	Handler       map[string]string `short:"H" env:"CHECKS4SHELL_ACTION_HANDLERS" help:"Shell command to run for an action, in the form of identifier=command" required:""`
+
+	stdout io.Writer
+	lock   *sync.Mutex
+	wg     *sync.WaitGroup
+}
+
+// AfterApply will run on CLI and initialise the missing properties
+func (s *ServeActions) AfterApply() error {
+	s.init()
+	return nil
+}
+
+func (s *ServeActions) init() {
+	if s.stdout == nil {
+		s.stdout = os.Stdout
+	}
+	if s.lock == nil {
+		s.lock = &sync.Mutex{}
+	}
+	if s.wg == nil {
+		s.wg = &sync.WaitGroup{}
+	}
+}
+
+// Run serves the webhook receiver
+func (s *ServeActions) Run() error {
+	return errors.WithStack(serveUntilSignal(s.Listen, s.handler(), s.stdout, s.wg))
+}
+
+func (s *ServeActions) handler() http.Handler {
+	return &webhookHandler{
+		secret: []byte(s.WebhookSecret),
+		handle: s.handle,
+	}
+}
+
+func (s *ServeActions) handle(event any) (int, string) {
+	e, ok := event.(*github.CheckRunEvent)
+	if !ok || e.GetAction() != checkRunActionRequestedAction {
+		return http.StatusOK, "ignored"
+	}
+
+	identifier := e.GetRequestedAction().Identifier
+	command, ok := s.Handler[identifier]
+	if !ok {
+		return http.StatusNotFound, fmt.Sprintf("no handler for action %s", identifier)
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.dispatch(identifier, command, e)
+	}()
+
+	return http.StatusAccepted, fmt.Sprintf("dispatched action %s", identifier)
+}
+
+// dispatch runs the command with the details of the check run exposed as environment variables,
+// the same ones the run command reads, so the handler could simply invoke checks4shell run again
+func (s *ServeActions) dispatch(identifier, command string, e *github.CheckRunEvent) {
+	out := &prefixWriter{prefix: fmt.Sprintf("[%s] ", identifier), out: s.stdout, lock: s.lock, lineStart: true}
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	cmd.Env = append(os.Environ(), checkRunEnv(e.GetRepo(), e.GetCheckRun())...)
+	cmd.Env = append(cmd.Env, "CHECKS4SHELL_ACTION_IDENTIFIER="+identifier)
+
+	err := cmd.Run()
+	if err != nil {
+		_, _ = fmt.Fprintf(out, "action %s failed: %v\n", identifier, err)
+		return
+	}
+	_, _ = fmt.Fprintf(out, "action %s finished\n", identifier)
+}
+
+// commitEnv returns the environment variables identifying the commit
+func commitEnv(repo *github.Repository, sha string) []string {
+	return []string{
+		"CHECKS4SHELL_OWNER=" + repo.GetOwner().GetLogin(),
+		"CHECKS4SHELL_REPOSITORY=" + repo.GetName(),
+		"CHECKS4SHELL_COMMIT_SHA=" + sha,
+	}
+}
+
+// checkRunEnv returns the environment variables identifying the check run
+func checkRunEnv(repo *github.Repository, checkRun *github.CheckRun) []string {
+	return append(commitEnv(repo, checkRun.GetHeadSHA()),
+		"CHECKS4SHELL_NAME="+checkRun.GetName(),
+		"CHECKS4SHELL_EXTERNAL_ID="+checkRun.GetExternalID(),
+		"CHECKS4SHELL_DETAILS_URL="+checkRun.GetDetailsURL(),
+		"CHECKS4SHELL_CHECK_RUN_ID="+strconv.FormatInt(checkRun.GetID(), 10),
+	)
+}
@@ -0,0 +1,105 @@
+package run
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/google/go-github/v64/github"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sampleWebhookSecret = "sampleWebhookSecret"
+
+// newWebhookRequest builds a webhook delivery of the given event to the url signed with the secret
+func newWebhookRequest(t *testing.T, url string, secret string, eventType string, event any) *http.Request {
+	t.Helper()
+	payload, err := json.Marshal(event)
+	require.NoError(t, err)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(github.EventTypeHeader, eventType)
+	req.Header.Set(github.SHA256SignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+func sampleCheckRunEvent(action string, identifier string) *github.CheckRunEvent {
+	return &github.CheckRunEvent{
+		Action: github.String(action),
+		CheckRun: &github.CheckRun{
+			ID:         github.Int64(42),
+			Name:       github.String(sampleName),
+			HeadSHA:    github.String(sampleHeadShA),
+			ExternalID: github.String(sampleExternalID),
+		},
+		Repo: &github.Repository{
+			Name:  github.String(sampleRepo),
+			Owner: &github.User{Login: github.String(sampleOwner)},
+		},
+		RequestedAction: &github.RequestedAction{Identifier: identifier},
+	}
+}
+
+func newServeActions(handlers map[string]string) (*ServeActions, *bytes.Buffer) {
+	stdout := &bytes.Buffer{}
+	s := &ServeActions{
+		WebhookSecret: sampleWebhookSecret,
+		Handler:       handlers,
+		stdout:        stdout,
+	}
+	s.init()
+	return s, stdout
+}
+
+func TestServeActionsDispatchesHandler(t *testing.T) {
+	t.Parallel()
+	out := filepath.Join(t.TempDir(), "out")
+	s, stdout := newServeActions(map[string]string{
+		"debug": `echo "$CHECKS4SHELL_ACTION_IDENTIFIER $CHECKS4SHELL_OWNER $CHECKS4SHELL_REPOSITORY $CHECKS4SHELL_COMMIT_SHA $CHECKS4SHELL_CHECK_RUN_ID" > ` + out,
+	})
+
+	rec := httptest.NewRecorder()
+	s.handler().ServeHTTP(rec, newWebhookRequest(t, "/", sampleWebhookSecret, "check_run", sampleCheckRunEvent("requested_action", "debug")))
+	require.Equal(t, http.StatusAccepted, rec.Code)
+
+	s.wg.Wait()
+	content, err := os.ReadFile(out)
+	require.NoError(t, err)
+	require.Equal(t, "debug sampleOwner sampleRepo sampleHeadShA 42\n", string(content))
+	require.Equal(t, "[debug] action debug finished\n", stdout.String())
+}
+
+func TestServeActionsRejectsInvalidSignature(t *testing.T) {
+	t.Parallel()
+	s, _ := newServeActions(map[string]string{"debug": "true"})
+
+	rec := httptest.NewRecorder()
+	s.handler().ServeHTTP(rec, newWebhookRequest(t, "/", "wrong secret", "check_run", sampleCheckRunEvent("requested_action", "debug")))
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestServeActionsIgnoresOtherEvents(t *testing.T) {
+	t.Parallel()
+	s, _ := newServeActions(map[string]string{"debug": "true"})
+
+	rec := httptest.NewRecorder()
+	s.handler().ServeHTTP(rec, newWebhookRequest(t, "/", sampleWebhookSecret, "check_run", sampleCheckRunEvent("created", "")))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "ignored", strings.TrimSpace(rec.Body.String()))
+
+	rec = httptest.NewRecorder()
+	s.handler().ServeHTTP(rec, newWebhookRequest(t, "/", sampleWebhookSecret, "check_run", sampleCheckRunEvent("requested_action", "unknown")))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
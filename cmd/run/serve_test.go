@@ -0,0 +1,143 @@
+package run
+
+import (
+	"bytes"
+	"github.com/coder/quartz"
+	"github.com/google/go-github/v64/github"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newServe(t *testing.T, checks []*MultiCheck) (*Serve, *httptest.Server) {
+	t.Helper()
+	clock := quartz.NewMock(t)
+	clock.Set(time.Now())
+	s := &Serve{
+		WebhookSecret:   sampleWebhookSecret,
+		UpdateFrequency: 5 * time.Second,
+		Concurrency:     2,
+		clock:           clock,
+		checks:          checks,
+		stdout:          &bytes.Buffer{},
+		stdoutLock:      &sync.Mutex{},
+		treeLock:        &sync.Mutex{},
+		wg:              &sync.WaitGroup{},
+		checksService:   NewMockChecksService(clock),
+		isAuthenticated: true,
+	}
+	server := httptest.NewServer(s.handler())
+	t.Cleanup(server.Close)
+	return s, server
+}
+
+// createdCheckRuns returns the create options sent to the checks service
+func createdCheckRuns(t *testing.T, s *MockChecksService) []MockCall {
+	t.Helper()
+	out := make([]MockCall, 0)
+	for _, r := range s.Calls() {
+		if _, ok := r.Options.(github.CreateCheckRunOptions); ok {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func deliver(t *testing.T, server *httptest.Server, eventType string, event any) int {
+	t.Helper()
+	resp, err := server.Client().Do(newWebhookRequest(t, server.URL, sampleWebhookSecret, eventType, event))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestServeRerunsRequestedCheckRun(t *testing.T) {
+	s, server := newServe(t, []*MultiCheck{
+		{Name: sampleName, Title: sampleTitle, Command: command(t, "echo", "rerun")},
+		{Name: "other", Command: command(t, "echo", "other")},
+	})
+
+	require.Equal(t, http.StatusAccepted, deliver(t, server, "check_run", sampleCheckRunEvent("rerequested", "")))
+	s.wg.Wait()
+
+	created := createdCheckRuns(t, s.checksService.(*MockChecksService))
+	require.Len(t, created, 1)
+	require.Equal(t, sampleOwner, created[0].Owner)
+	require.Equal(t, sampleRepo, created[0].Repository)
+	opt := created[0].Options.(github.CreateCheckRunOptions)
+	require.Equal(t, sampleName, opt.Name)
+	require.Equal(t, sampleHeadShA, opt.HeadSHA)
+	require.Equal(t, map[string]string{sampleName: checksConclusionSuccess}, lastConclusions(t, s.checksService.(*MockChecksService)))
+}
+
+func TestServeRerunsWithCommitEnv(t *testing.T) {
+	s, server := newServe(t, []*MultiCheck{
+		{Name: sampleName, Command: []string{"sh", "-c", "echo $CHECKS4SHELL_COMMIT_SHA $CHECKS4SHELL_CHECK_RUN_ID"}},
+	})
+
+	require.Equal(t, http.StatusAccepted, deliver(t, server, "check_run", sampleCheckRunEvent("rerequested", "")))
+	s.wg.Wait()
+	require.Contains(t, s.stdout.(*bytes.Buffer).String(), "["+sampleName+"] sampleHeadShA 42\n")
+}
+
+func TestServeRerunsRequestedCheckSuite(t *testing.T) {
+	s, server := newServe(t, []*MultiCheck{
+		{Name: "one", Command: command(t, "echo", "1")},
+		{Name: "two", Command: command(t, "errorm", "1", "2")},
+	})
+
+	event := &github.CheckSuiteEvent{
+		Action:     github.String("rerequested"),
+		CheckSuite: &github.CheckSuite{HeadSHA: github.String(sampleHeadShA)},
+		Repo: &github.Repository{
+			Name:  github.String(sampleRepo),
+			Owner: &github.User{Login: github.String(sampleOwner)},
+		},
+	}
+	require.Equal(t, http.StatusAccepted, deliver(t, server, "check_suite", event))
+	s.wg.Wait()
+
+	require.Equal(t, map[string]string{
+		"one": checksConclusionSuccess,
+		"two": checksConclusionFailure,
+	}, lastConclusions(t, s.checksService.(*MockChecksService)))
+}
+
+func TestServeIgnoresUnknownChecks(t *testing.T) {
+	s, server := newServe(t, []*MultiCheck{{Name: "other", Command: command(t, "echo", "other")}})
+
+	require.Equal(t, http.StatusNotFound, deliver(t, server, "check_run", sampleCheckRunEvent("rerequested", "")))
+	require.Equal(t, http.StatusOK, deliver(t, server, "check_run", sampleCheckRunEvent("completed", "")))
+	s.wg.Wait()
+	require.Empty(t, s.checksService.(*MockChecksService).Calls())
+
+	resp, err := server.Client().Do(newWebhookRequest(t, server.URL, "wrong secret", "check_run", sampleCheckRunEvent("rerequested", "")))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestServeSerializesReruns(t *testing.T) {
+	// the command fails when another rerun holds the working tree
+	lock := filepath.Join(t.TempDir(), "lock")
+	s, server := newServe(t, []*MultiCheck{
+		{Name: sampleName, Command: []string{"sh", "-c", "mkdir " + lock + " && sleep 0.2 && rmdir " + lock}},
+		{Name: "other", Command: []string{"sh", "-c", "mkdir " + lock + " && sleep 0.2 && rmdir " + lock}},
+	})
+
+	other := sampleCheckRunEvent("rerequested", "")
+	other.CheckRun.Name = github.String("other")
+	other.CheckRun.HeadSHA = github.String("otherHeadSHA")
+	require.Equal(t, http.StatusAccepted, deliver(t, server, "check_run", sampleCheckRunEvent("rerequested", "")))
+	require.Equal(t, http.StatusAccepted, deliver(t, server, "check_run", other))
+	s.wg.Wait()
+
+	require.Equal(t, map[string]string{
+		sampleName: checksConclusionSuccess,
+		"other":    checksConclusionSuccess,
+	}, lastConclusions(t, s.checksService.(*MockChecksService)))
+}
@@ -0,0 +1,52 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// shutdownTimeout is how long the requests being served are given to finish once a signal is received
+const shutdownTimeout = 30 * time.Second
+
+// serveUntilSignal serves the handler on the given address until an interrupt or terminate signal is received,
+// then waits for the in flight work tracked by the wait group to finish
+func serveUntilSignal(addr string, handler http.Handler, stdout io.Writer, wg *sync.WaitGroup) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+	_, _ = fmt.Fprintf(stdout, "listening on %s\n", addr)
+
+	select {
+	case err := <-serveErr:
+		return errors.Wrap(err, "error serving")
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	err := server.Shutdown(shutdownCtx)
+	if err != nil {
+		return errors.Wrap(err, "error shutting down the server")
+	}
+
+	wg.Wait()
+	return nil
+}
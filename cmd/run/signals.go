@@ -0,0 +1,112 @@
+package run
+
+import (
+	"github.com/pkg/errors"
+	"os"
+	"strings"
+	"syscall"
+)
+
+const (
+	secondSignalForward = "forward"
+	secondSignalKill    = "kill"
+)
+
+// defaultForwardedSignals are the signals forwarded to the command when none are given
+var defaultForwardedSignals = []string{"INT", "TERM", "HUP", "QUIT", "USR1", "USR2"}
+
+// defaultSignalPolicy forwards the default signals as they are
+var defaultSignalPolicy = mustSignalPolicy(newSignalPolicy(nil, nil, ""))
+
+// signalPolicy decides which of the signals received are forwarded to the command, and as which signal
+type signalPolicy struct {
+	forward      map[os.Signal]bool
+	translate    map[os.Signal]os.Signal
+	killOnSecond bool
+}
+
+// newSignalPolicy returns the policy forwarding the named signals, the default ones when there are none, translating
+// them with the FROM=TO names given and killing the command on the second signal received when asked to
+func newSignalPolicy(forward []string, translate map[string]string, second string) (*signalPolicy, error) {
+	if len(forward) == 0 {
+		forward = defaultForwardedSignals
+	}
+
+	p := &signalPolicy{
+		forward:   map[os.Signal]bool{},
+		translate: map[os.Signal]os.Signal{},
+	}
+	for _, name := range forward {
+		s, err := parseSignal(name)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		p.forward[s] = true
+	}
+
+	for from, to := range translate {
+		fromSignal, err := parseSignal(from)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		toSignal, err := parseSignal(to)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		// a signal translated is forwarded, or there would be nothing to translate
+		p.forward[fromSignal] = true
+		p.translate[fromSignal] = toSignal
+	}
+
+	switch second {
+	case "", secondSignalForward:
+	case secondSignalKill:
+		p.killOnSecond = true
+	default:
+		return nil, errors.Errorf("unknown second signal policy %q", second)
+	}
+
+	return p, nil
+}
+
+func mustSignalPolicy(p *signalPolicy, err error) *signalPolicy {
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// parseSignal returns the signal of the given name, with or without the SIG prefix
+func parseSignal(name string) (os.Signal, error) {
+	s, ok := signalNames[strings.TrimPrefix(strings.ToUpper(name), "SIG")]
+	if !ok {
+		return nil, errors.Errorf("unknown signal %q", name)
+	}
+	return s, nil
+}
+
+// signals returns the signals to be notified of
+func (p *signalPolicy) signals() []os.Signal {
+	signals := make([]os.Signal, 0, len(p.forward))
+	for s := range p.forward {
+		signals = append(signals, s)
+	}
+	return signals
+}
+
+// forwards tells whether the signal received is forwarded to the command
+func (p *signalPolicy) forwards(s os.Signal) bool {
+	return p.forward[s]
+}
+
+// apply returns the signal sent to the command for the forwarded signal received, counting from 1
+func (p *signalPolicy) apply(s os.Signal, received int) os.Signal {
+	if received > 1 && p.killOnSecond {
+		return syscall.SIGKILL
+	}
+
+	if translated, ok := p.translate[s]; ok {
+		return translated
+	}
+	return s
+}
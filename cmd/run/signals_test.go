@@ -0,0 +1,78 @@
+//go:build unix
+
+package run
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSignalPolicy(t *testing.T) {
+	t.Parallel()
+	p, err := newSignalPolicy(nil, map[string]string{"sigterm": "INT"}, "")
+	require.NoError(t, err)
+	require.True(t, p.forwards(syscall.SIGUSR1))
+	require.False(t, p.forwards(syscall.SIGWINCH))
+	require.False(t, p.forwards(syscall.SIGCHLD))
+	require.Equal(t, os.Interrupt, p.apply(syscall.SIGTERM, 1))
+	require.Equal(t, os.Interrupt, p.apply(syscall.SIGTERM, 2))
+	require.Len(t, p.signals(), 6)
+
+	p, err = newSignalPolicy([]string{"INT"}, map[string]string{"WINCH": "HUP"}, secondSignalKill)
+	require.NoError(t, err)
+	require.True(t, p.forwards(syscall.SIGWINCH))
+	require.False(t, p.forwards(syscall.SIGTERM))
+	require.Equal(t, syscall.SIGHUP, p.apply(syscall.SIGWINCH, 1))
+	require.Equal(t, syscall.SIGKILL, p.apply(os.Interrupt, 2))
+
+	_, err = newSignalPolicy([]string{"NOPE"}, nil, "")
+	require.Error(t, err)
+	_, err = newSignalPolicy(nil, map[string]string{"TERM": "NOPE"}, "")
+	require.Error(t, err)
+	_, err = newSignalPolicy(nil, nil, "ignore")
+	require.Error(t, err)
+}
+
+func runWithSignals(t *testing.T, r *Run, signals ...os.Signal) error {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() {
+		done <- r.Run(&Config{})
+	}()
+
+	// the command prints ready once its signal handlers are set up
+	waitForOutput(t, r.screen, "ready")
+	for _, s := range signals {
+		r.sigChan <- s
+	}
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(10 * time.Second):
+		t.Fatal("command still running")
+		return nil
+	}
+}
+
+func TestSignalTranslation(t *testing.T) {
+	t.Parallel()
+	r, _ := newSampleRun(t, command(t, "capture-signal")...)
+	r.TranslateSignal = map[string]string{"TERM": "INT"}
+
+	require.Error(t, runWithSignals(t, r, syscall.SIGWINCH, syscall.SIGTERM))
+	completed := lastUpdate(t, r)
+	require.Equal(t, "```bash\nready\ncapture signal: interrupt\n```", completed.GetOutput().GetText())
+}
+
+func TestSecondSignalKill(t *testing.T) {
+	t.Parallel()
+	r, _ := newSampleRun(t, command(t, "ignore-signals")...)
+	r.SecondSignal = secondSignalKill
+
+	require.Error(t, runWithSignals(t, r, os.Interrupt, os.Interrupt))
+	require.Equal(t, "signal: killed", r.exitStatus)
+}
@@ -0,0 +1,156 @@
+package run
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/alecthomas/kong"
+	"github.com/google/go-github/v64/github"
+	"github.com/pkg/errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+const spoolFileFormat = "%020d-%s.json"
+
+var spoolNameReplacer = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// isNetworkError tells whether the request failed to reach GitHub, GitHub failed to handle it or limited its rate,
+// any other error rejects the payload and is returned right away
+func isNetworkError(err error) bool {
+	var rateLimitErr *github.RateLimitError
+	var abuseRateLimitErr *github.AbuseRateLimitError
+	if errors.As(err, &rateLimitErr) || errors.As(err, &abuseRateLimitErr) {
+		return true
+	}
+
+	var respErr *github.ErrorResponse
+	if errors.As(err, &respErr) {
+		if respErr.Response == nil {
+			return false
+		}
+		return respErr.Response.StatusCode >= 500 || respErr.Response.StatusCode == http.StatusTooManyRequests
+	}
+
+	var netErr net.Error
+	var urlErr *url.Error
+	return errors.As(err, &netErr) || errors.As(err, &urlErr)
+}
+
+// spoolOnFailure keeps the execution going when GitHub can't be reached and a spool directory is given,
+// the updates in progress are dropped and the last one is written to the spool directory
+func (r *Run) spoolOnFailure(sendErr error, rec *recordedPayload, conclusion string) error {
+	if r.SpoolDir == "" || !isNetworkError(sendErr) {
+		return sendErr
+	}
+
+	if conclusion == "" {
+		_, _ = fmt.Fprintf(os.Stderr, "\nSkipping update, GitHub can't be reached: %v\n", sendErr)
+		return nil
+	}
+
+	path, err := r.spool(rec)
+	if err != nil {
+		return errors.Wrapf(err, "error spooling last update after: %v", sendErr)
+	}
+
+	_, _ = fmt.Fprintf(os.Stderr, "\nGitHub can't be reached, last update spooled to %s to be sent with the flush command: %v\n", path, sendErr)
+	return nil
+}
+
+// spool writes the payload to a new file of the spool directory and returns its path
+func (r *Run) spool(rec *recordedPayload) (string, error) {
+	r.stamp(rec)
+	content, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	err = os.MkdirAll(r.SpoolDir, 0700)
+	if err != nil {
+		return "", errors.Wrap(err, "error creating spool directory")
+	}
+
+	path := filepath.Join(r.SpoolDir, fmt.Sprintf(spoolFileFormat, rec.Time.UnixNano(), spoolNameReplacer.ReplaceAllString(r.Name, "_")))
+	err = os.WriteFile(path, content, 0600)
+	if err != nil {
+		return "", errors.Wrap(err, "error writing spool file")
+	}
+
+	return path, nil
+}
+
+// Flush is the struct for the flush command, it sends the updates spooled while GitHub couldn't be reached
+type Flush struct {
+	SpoolDir string `arg:"" type:"existingdir" help:"Spool directory given to the run command with --spool-dir"`
+
+	stdout          io.Writer
+	checksService   ChecksService
+	isAuthenticated bool
+}
+
+// AfterApply will run on CLI and initialise the missing properties
+func (f *Flush) AfterApply(_ *kong.Context, cfg *Config) error {
+	if f.stdout == nil {
+		f.stdout = os.Stdout
+	}
+
+	f.checksService = cfg.ChecksService
+	f.isAuthenticated = cfg.IsAuthenticated
+
+	return nil
+}
+
+// Run sends the spooled updates in the order they were spooled, creating the check runs that never were,
+// and removes them once sent
+func (f *Flush) Run() error {
+	if !f.isAuthenticated {
+		return errors.New("flushing requires the GitHub App credentials")
+	}
+
+	paths, err := filepath.Glob(filepath.Join(f.SpoolDir, "*.json"))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		err = f.flush(path)
+		if err != nil {
+			return errors.Wrapf(err, "error flushing %s", path)
+		}
+	}
+
+	return nil
+}
+
+func (f *Flush) flush(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	rec := &recordedPayload{}
+	err = json.Unmarshal(content, rec)
+	if err != nil {
+		return errors.Wrap(err, "error parsing spool file")
+	}
+
+	id, err := sendRecordedPayload(f.checksService, rec.Owner, rec.Repository, rec.CheckRunID, rec)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	err = os.Remove(path)
+	if err != nil {
+		return errors.Wrap(err, "error removing spool file")
+	}
+
+	_, err = fmt.Fprintf(f.stdout, "flushed %s to check run %d\n", filepath.Base(path), id)
+	return errors.WithStack(err)
+}
@@ -0,0 +1,128 @@
+package run
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/google/go-github/v64/github"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var errOffline = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+func readSpool(t *testing.T, dir string) []*recordedPayload {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	var records []*recordedPayload
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		rec := &recordedPayload{}
+		require.NoError(t, json.Unmarshal(content, rec))
+		records = append(records, rec)
+	}
+	return records
+}
+
+func TestSpoolWhenCreationNeverSucceeded(t *testing.T) {
+	t.Parallel()
+	spool := t.TempDir()
+	r, _ := newSampleRun(t, command(t, "echo", "offline")...)
+	r.SpoolDir = spool
+	service := getCheckServiceOutFromRun(t, r)
+	service.Fail = func(string) error { return errOffline }
+
+	require.NoError(t, r.Run(&Config{}))
+	require.Empty(t, service.Calls())
+	records := readSpool(t, spool)
+	require.Len(t, records, 1)
+	require.Equal(t, recordKindCreate, records[0].Kind)
+	require.Equal(t, checksConclusionSuccess, records[0].Create.GetConclusion())
+
+	stdout := &bytes.Buffer{}
+	service.Fail = nil
+	f := &Flush{SpoolDir: spool, stdout: stdout, checksService: service, isAuthenticated: true}
+	require.NoError(t, f.Run())
+	require.Contains(t, stdout.String(), "to check run 23\n")
+	runs := service.Calls()
+	require.Len(t, runs, 1)
+	created := runs[0].Options.(github.CreateCheckRunOptions)
+	require.Equal(t, checksConclusionSuccess, created.GetConclusion())
+	require.Contains(t, created.GetOutput().GetText(), "offline")
+	require.Empty(t, readSpool(t, spool))
+}
+
+func TestSpoolLastUpdate(t *testing.T) {
+	t.Parallel()
+	spool := t.TempDir()
+	r, _ := newSampleRun(t, command(t, "errorm", "2", "failed")...)
+	r.SpoolDir = spool
+	service := getCheckServiceOutFromRun(t, r)
+	service.Fail = func(method string) error {
+		if method == "UpdateCheckRun" {
+			return &github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusBadGateway}}
+		}
+		return nil
+	}
+
+	require.ErrorContains(t, r.Run(&Config{}), "exit status 2")
+	require.Len(t, service.Calls(), 1)
+	records := readSpool(t, spool)
+	require.Len(t, records, 1)
+	require.Equal(t, recordKindUpdate, records[0].Kind)
+	require.Equal(t, int64(23), records[0].CheckRunID)
+
+	service.Fail = nil
+	f := &Flush{SpoolDir: spool, stdout: &bytes.Buffer{}, checksService: service, isAuthenticated: true}
+	require.NoError(t, f.Run())
+	runs := service.Calls()
+	require.Len(t, runs, 2)
+	require.Equal(t, int64(23), runs[1].CheckRunID)
+	completed := runs[1].Options.(github.UpdateCheckRunOptions)
+	require.Equal(t, checksConclusionFailure, completed.GetConclusion())
+}
+
+func TestSpoolIgnoresRejectedPayloads(t *testing.T) {
+	t.Parallel()
+	spool := t.TempDir()
+	r, _ := newSampleRun(t, command(t, "echo", "rejected")...)
+	r.SpoolDir = spool
+	getCheckServiceOutFromRun(t, r).Fail = func(string) error {
+		return &github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusUnprocessableEntity}}
+	}
+
+	require.ErrorContains(t, r.Run(&Config{}), "error creating check Run")
+	require.Empty(t, readSpool(t, spool))
+}
+
+func TestIsNetworkError(t *testing.T) {
+	t.Parallel()
+	for name, tc := range map[string]struct {
+		err      error
+		expected bool
+	}{
+		"dial":         {errOffline, true},
+		"url":          {errors.WithStack(&url.Error{Op: "Post", URL: "https://api.github.com", Err: errors.New("EOF")}), true},
+		"server error": {mockError(http.StatusBadGateway, "Bad Gateway"), true},
+		"too many":     {mockError(http.StatusTooManyRequests, "Too Many Requests"), true},
+		"rate limit":   {&github.RateLimitError{}, true},
+		"rejected":     {mockError(http.StatusUnprocessableEntity, "Invalid request."), false},
+		"not found":    {mockError(http.StatusNotFound, "Not Found"), false},
+		"unknown":      {errors.New("invalid payload"), false},
+	} {
+		require.Equal(t, tc.expected, isNetworkError(tc.err), name)
+	}
+}
+
+func TestFlushRequiresCredentials(t *testing.T) {
+	t.Parallel()
+	f := &Flush{SpoolDir: t.TempDir()}
+	require.ErrorContains(t, f.Run(), "flushing requires the GitHub App credentials")
+}
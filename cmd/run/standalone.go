@@ -0,0 +1,275 @@
+package run
+
+import (
+	"encoding/json"
+	"github.com/alecthomas/kong"
+	"github.com/coder/quartz"
+	"github.com/pkg/errors"
+	"os"
+)
+
+// StateFlags holds the flags locating the state shared by the create, update and complete commands
+type StateFlags struct {
+	StateFile string `env:"CHECKS4SHELL_STATE_FILE" default:".checks4shell-state.json" help:"File keeping the state of the check run between the create, update and complete commands"`
+}
+
+// OutputFlags holds the output flags shared by the create, update and complete commands
+type OutputFlags struct {
+	Title           string `short:"t" env:"CHECKS4SHELL_TITLE" help:"Output title of the check"`
+	Summary         string `short:"s" env:"CHECKS4SHELL_SUMMARY" help:"Output summary of the check can either be a fixed string or a file filled with content"`
+	Text            string `short:"x" help:"Text appended to the output of the check"`
+	TextFile        string `type:"existingfile" help:"File whose content is appended to the output of the check"`
+	Images          string `short:"i" env:"CHECKS4SHELL_IMAGES" help:"Output image json directory of the check, files inside will be presented in naming order. the json structure is the same as github.CheckRunImage"`
+	Annotations     string `short:"a" env:"CHECKS4SHELL_ANNOTATIONS" help:"Output annotation directory of the check, files inside will be presented in naming order. the json structure is the same as github.CheckRunAnnotation. Annotations are added to the ones sent before"`
+	SyntaxHighlight string `short:"l" env:"CHECKS4SHELL_SYNTAX_HIGHLIGHT" help:"syntax highlight you want to use for the terminal output"`
+	Debug           bool   `short:"d" help:"Enable debug mode"`
+	MaskFlags
+}
+
+// checkRunState is the state of a check run persisted between the create, update and complete commands
+type checkRunState struct {
+	Owner           string `json:"owner"`
+	Repository      string `json:"repository"`
+	CommitSHA       string `json:"commit_sha"`
+	Name            string `json:"name"`
+	Title           string `json:"title"`
+	Summary         string `json:"summary"`
+	DetailsURL      string `json:"details_url,omitempty"`
+	ExternalID      string `json:"external_id,omitempty"`
+	SyntaxHighlight string `json:"syntax_highlight,omitempty"`
+	CheckRunID      int64  `json:"check_run_id"`
+	Text            string `json:"text,omitempty"`
+}
+
+func readState(path string) (*checkRunState, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading state file, was the check run created?")
+	}
+
+	state := &checkRunState{}
+	err = json.Unmarshal(content, state)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing state file")
+	}
+
+	return state, nil
+}
+
+func (s *checkRunState) write(path string) error {
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	err = os.WriteFile(path, content, 0600)
+	if err != nil {
+		return errors.Wrap(err, "error writing state file")
+	}
+
+	return nil
+}
+
+// apply updates the state with the output flags given
+func (s *checkRunState) apply(flags *OutputFlags) {
+	if flags.Title != "" {
+		s.Title = flags.Title
+	}
+	if flags.Summary != "" {
+		s.Summary = flags.Summary
+	}
+	if flags.SyntaxHighlight != "" {
+		s.SyntaxHighlight = flags.SyntaxHighlight
+	}
+}
+
+// standalone holds what the create, update and complete commands need to talk to the checks service
+type standalone struct {
+	clock           quartz.Clock
+	checksService   ChecksService
+	isAuthenticated bool
+}
+
+func (s *standalone) init(cfg *Config) {
+	if s.clock == nil {
+		s.clock = quartz.NewReal()
+	}
+	s.checksService = cfg.ChecksService
+	s.isAuthenticated = cfg.IsAuthenticated
+}
+
+// newRun returns a run of the state, with the text appended to its screen, so that the
+// check run could be sent the same way as the run command does
+func (s *standalone) newRun(state *checkRunState, flags *OutputFlags) (*Run, error) {
+	screen, err := NewSyncScreen()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	text := state.Text
+	if text != "" {
+		text += "\n"
+	}
+	text += flags.Text
+	if flags.TextFile != "" {
+		content, err := os.ReadFile(flags.TextFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "error reading text file")
+		}
+		text += string(content)
+	}
+
+	masker := flags.newMasker()
+	_, err = screen.Write([]byte(masker.mask(text)))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	state.Text = truncateOutput(screen.ReadScreen(), outputLimit)
+
+	return &Run{
+		Owner:           state.Owner,
+		Repository:      state.Repository,
+		CommitSHA:       state.CommitSHA,
+		Name:            state.Name,
+		Title:           state.Title,
+		DetailsURL:      state.DetailsURL,
+		ExternalID:      state.ExternalID,
+		Summary:         state.Summary,
+		Images:          flags.Images,
+		Annotations:     flags.Annotations,
+		SyntaxHighlight: state.SyntaxHighlight,
+		Debug:           flags.Debug,
+		screen:          screen,
+		clock:           s.clock,
+		checksService:   s.checksService,
+		isAuthenticated: s.isAuthenticated,
+		runId:           state.CheckRunID,
+		masker:          masker,
+	}, nil
+}
+
+// Create is the struct for the create command, it creates a check run and records it in the state file
+type Create struct {
+	Owner      string `short:"o" env:"CHECKS4SHELL_OWNER" required:"" help:"The owner of the target GitHub repo"`
+	Repository string `short:"r" env:"CHECKS4SHELL_REPOSITORY" required:"" help:"The target GitHub repository"`
+	CommitSHA  string `short:"c" env:"CHECKS4SHELL_COMMIT_SHA" required:"" help:"The target SHA of the check Run to be created"`
+	Name       string `short:"n" env:"CHECKS4SHELL_NAME" required:"" help:"Name of the check Run"`
+	DetailsURL string `short:"u" env:"CHECKS4SHELL_DETAILS_URL" help:"Details URL of the check" `
+	ExternalID string `short:"e" env:"CHECKS4SHELL_EXTERNAL_ID" help:"External ID of the check" `
+	StateFlags
+	OutputFlags
+
+	standalone
+}
+
+// AfterApply will run on CLI and initialise the missing properties
+func (c *Create) AfterApply(_ *kong.Context, cfg *Config) error {
+	c.init(cfg)
+	return nil
+}
+
+// Run creates the check run
+func (c *Create) Run() error {
+	if c.Title == "" {
+		return errors.New("--title is required to create a check run")
+	}
+
+	state := &checkRunState{
+		Owner:      c.Owner,
+		Repository: c.Repository,
+		CommitSHA:  c.CommitSHA,
+		Name:       c.Name,
+		DetailsURL: c.DetailsURL,
+		ExternalID: c.ExternalID,
+	}
+	state.apply(&c.OutputFlags)
+
+	r, err := c.newRun(state, &c.OutputFlags)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	err = r.createCheckRun("")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	state.CheckRunID = r.runId
+	return errors.WithStack(state.write(c.StateFile))
+}
+
+// Update is the struct for the update command, it appends to the output of the check run in the state file
+type Update struct {
+	StateFlags
+	OutputFlags
+
+	standalone
+}
+
+// AfterApply will run on CLI and initialise the missing properties
+func (u *Update) AfterApply(_ *kong.Context, cfg *Config) error {
+	u.init(cfg)
+	return nil
+}
+
+// Run updates the check run
+func (u *Update) Run() error {
+	state, err := readState(u.StateFile)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	state.apply(&u.OutputFlags)
+
+	r, err := u.newRun(state, &u.OutputFlags)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	err = r.updateCheckRun("")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return errors.WithStack(state.write(u.StateFile))
+}
+
+// Complete is the struct for the complete command, it concludes the check run in the state file
+type Complete struct {
+	Conclusion string `short:"C" env:"CHECKS4SHELL_CONCLUSION" required:"" enum:"success,failure,neutral,cancelled,skipped,timed_out,action_required" help:"Conclusion of the check run, one of ${enum}"`
+	StateFlags
+	OutputFlags
+
+	standalone
+}
+
+// AfterApply will run on CLI and initialise the missing properties
+func (c *Complete) AfterApply(_ *kong.Context, cfg *Config) error {
+	c.init(cfg)
+	return nil
+}
+
+// Run completes the check run and removes the state file
+func (c *Complete) Run() error {
+	state, err := readState(c.StateFile)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	state.apply(&c.OutputFlags)
+
+	r, err := c.newRun(state, &c.OutputFlags)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	err = r.updateCheckRun(c.Conclusion)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	err = os.Remove(c.StateFile)
+	if err != nil {
+		return errors.Wrap(err, "error removing state file")
+	}
+
+	return nil
+}
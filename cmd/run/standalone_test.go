@@ -0,0 +1,124 @@
+package run
+
+import (
+	"github.com/coder/quartz"
+	"github.com/google/go-github/v64/github"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newStandalone(t *testing.T, runId int64) standalone {
+	t.Helper()
+	clock := quartz.NewMock(t)
+	clock.Set(time.Now())
+	return standalone{
+		clock:           clock,
+		checksService:   newMockChecksService(clock, runId),
+		isAuthenticated: true,
+	}
+}
+
+func TestCreateUpdateComplete(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	stateFile := StateFlags{StateFile: filepath.Join(dir, "state.json")}
+	annotations := filepath.Join(dir, "annotations")
+	require.NoError(t, os.Mkdir(annotations, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(annotations, "a.json"), []byte(`{"path":"main.go","message":"broken"}`), 0644))
+	s := newStandalone(t, 18)
+
+	create := &Create{
+		Owner:       sampleOwner,
+		Repository:  sampleRepo,
+		CommitSHA:   sampleHeadShA,
+		Name:        sampleName,
+		StateFlags:  stateFile,
+		OutputFlags: OutputFlags{Title: sampleTitle, Summary: sampleSummary, Text: "step 1"},
+		standalone:  s,
+	}
+	require.NoError(t, create.Run())
+
+	state, err := readState(stateFile.StateFile)
+	require.NoError(t, err)
+	require.Equal(t, int64(18), state.CheckRunID)
+	require.Equal(t, "step 1", state.Text)
+
+	update := &Update{
+		StateFlags:  stateFile,
+		OutputFlags: OutputFlags{Text: "step 2", Annotations: annotations},
+		standalone:  s,
+	}
+	require.NoError(t, update.Run())
+
+	complete := &Complete{
+		Conclusion:  "neutral",
+		StateFlags:  stateFile,
+		OutputFlags: OutputFlags{Title: "done"},
+		standalone:  s,
+	}
+	require.NoError(t, complete.Run())
+	_, err = os.Stat(stateFile.StateFile)
+	require.True(t, os.IsNotExist(err))
+
+	runs := s.checksService.(*MockChecksService).Calls()
+	require.Len(t, runs, 3)
+
+	created := runs[0].Options.(github.CreateCheckRunOptions)
+	require.Equal(t, checksStatusInProgress, created.GetStatus())
+	require.Equal(t, processOutput("step 1", ""), created.GetOutput().GetText())
+
+	updated := runs[1].Options.(github.UpdateCheckRunOptions)
+	require.Equal(t, int64(18), runs[1].CheckRunID)
+	require.Equal(t, processOutput("step 1\nstep 2", ""), updated.GetOutput().GetText())
+	require.Equal(t, sampleSummary, updated.GetOutput().GetSummary())
+	require.Equal(t, []*github.CheckRunAnnotation{{Path: github.String("main.go"), Message: github.String("broken")}}, updated.GetOutput().Annotations)
+
+	completed := runs[2].Options.(github.UpdateCheckRunOptions)
+	require.Equal(t, "neutral", completed.GetConclusion())
+	require.Equal(t, checksStatusCompleted, completed.GetStatus())
+	require.Equal(t, "done", completed.GetOutput().GetTitle())
+	require.Nil(t, completed.GetOutput().Annotations)
+}
+
+func TestCreateMasksOutput(t *testing.T) {
+	t.Parallel()
+	stateFile := StateFlags{StateFile: filepath.Join(t.TempDir(), "state.json")}
+	s := newStandalone(t, 20)
+
+	create := &Create{
+		Owner:      sampleOwner,
+		Repository: sampleRepo,
+		CommitSHA:  sampleHeadShA,
+		Name:       sampleName,
+		StateFlags: stateFile,
+		OutputFlags: OutputFlags{
+			Title:     sampleTitle,
+			Summary:   "deployed with hidden-value",
+			Text:      "token hidden-value",
+			MaskFlags: MaskFlags{Mask: []string{"hidden-value"}},
+		},
+		standalone: s,
+	}
+	require.NoError(t, create.Run())
+
+	runs := s.checksService.(*MockChecksService).Calls()
+	created := runs[0].Options.(github.CreateCheckRunOptions)
+	require.Equal(t, processOutput("token ***", ""), created.GetOutput().GetText())
+	require.Contains(t, created.GetOutput().GetSummary(), "deployed with ***")
+
+	state, err := readState(stateFile.StateFile)
+	require.NoError(t, err)
+	require.Equal(t, "token ***", state.Text)
+}
+
+func TestUpdateWithoutCreate(t *testing.T) {
+	t.Parallel()
+	update := &Update{
+		StateFlags: StateFlags{StateFile: filepath.Join(t.TempDir(), "state.json")},
+		standalone: newStandalone(t, 19),
+	}
+	require.ErrorContains(t, update.Run(), "was the check run created?")
+}
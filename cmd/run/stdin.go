@@ -0,0 +1,109 @@
+package run
+
+import (
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const (
+	inputSectionHeader = "<details>\n<summary>input</summary>\n\n"
+	inputSectionFooter = "\n\n</details>\n\n"
+)
+
+// setInput gives the input of the run to the command. The input is teed into the input screen with --tee-stdin,
+// in which case it goes through a pipe so that waiting for the command never waits for the input to end.
+// With --pty, the pseudo-terminal is the input of the command and the input is typed into it once it started
+func (r *Run) setInput(cmd *exec.Cmd) (func() error, error) {
+	noFlush := func() error {
+		return nil
+	}
+	if r.stdin == nil || r.NoStdin {
+		return noFlush, nil
+	}
+
+	if r.inputScreen == nil && !r.Pty {
+		cmd.Stdin = r.stdin
+		return noFlush, nil
+	}
+
+	input := r.stdin
+	flush := noFlush
+	if r.inputScreen != nil {
+		var tee io.Writer = r.inputScreen
+		if r.masker != nil {
+			w := &maskingWriter{masker: r.masker, out: r.inputScreen}
+			tee, flush = w, w.Flush
+		}
+		input = io.TeeReader(r.stdin, tee)
+	}
+
+	if r.Pty {
+		r.ptyInput = input
+		return flush, nil
+	}
+
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating stdin pipe")
+	}
+
+	go func() {
+		// stops at the end of the input, or once the command is gone and the pipe broken
+		_, _ = io.Copy(pw, input)
+		_ = pw.Close()
+	}()
+
+	cmd.Stdin = pr
+	return func() error {
+		// the command has its own copy of the read end
+		_ = pr.Close()
+		return flush()
+	}, nil
+}
+
+// processInput renders the input teed with --tee-stdin into a collapsible section taking at most half the limit,
+// returning it along with the limit left to the output
+func processInput(text string, highlight string, limit int) (string, int) {
+	if text == "" {
+		return "", limit
+	}
+
+	budget := limit/2 - len(inputSectionHeader) - len(inputSectionFooter)
+	section := processOutputWithLimit(text, highlight, budget)
+
+	builder := strings.Builder{}
+	builder.WriteString(inputSectionHeader)
+	builder.WriteString(section)
+	builder.WriteString(inputSectionFooter)
+	return builder.String(), limit - builder.Len()
+}
+
+// typeInput writes the input into the master end of the pseudo-terminal as if it was typed, ending it with the end
+// of file character. It stops at the end of the input, or once the pseudo-terminal is closed
+func typeInput(master io.Writer, input io.Reader) {
+	last := byte('\n')
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := input.Read(buf)
+		if n > 0 {
+			last = buf[n-1]
+			_, writeErr := master.Write(buf[:n])
+			if writeErr != nil {
+				return
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	// the end of file character only ends the input at the start of a line, it sends the line being typed otherwise
+	eof := []byte{ptyEOF}
+	if last != '\n' {
+		eof = append(eof, ptyEOF)
+	}
+	_, _ = master.Write(eof)
+}
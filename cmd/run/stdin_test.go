@@ -0,0 +1,56 @@
+package run
+
+import (
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestStdin(t *testing.T) {
+	t.Parallel()
+	r, _ := newSampleRun(t, command(t, "upper")...)
+	r.stdin = strings.NewReader("generated\n")
+
+	require.NoError(t, r.Run(&Config{}))
+	completed := lastUpdate(t, r)
+	require.Equal(t, "```bash\nGENERATED\n```", completed.GetOutput().GetText())
+}
+
+func TestNoStdin(t *testing.T) {
+	t.Parallel()
+	r, _ := newSampleRun(t, command(t, "upper")...)
+	r.stdin = strings.NewReader("generated\n")
+	r.NoStdin = true
+
+	require.NoError(t, r.Run(&Config{}))
+	completed := lastUpdate(t, r)
+	require.Empty(t, completed.GetOutput().GetText())
+}
+
+func TestTeeStdin(t *testing.T) {
+	t.Parallel()
+	r, _ := newSampleRun(t, command(t, "upper")...)
+	r.stdin = strings.NewReader("pin 1234\n")
+	r.TeeStdin = true
+	r.Mask = []string{"1234"}
+
+	require.NoError(t, r.Run(&Config{}))
+	completed := lastUpdate(t, r)
+	require.Equal(t, inputSectionHeader+"```bash\npin ***\n```"+inputSectionFooter+"```bash\nPIN ***\n```", completed.GetOutput().GetText())
+}
+
+func TestProcessInput(t *testing.T) {
+	t.Parallel()
+	section, limit := processInput("", "", 100)
+	require.Empty(t, section)
+	require.Equal(t, 100, limit)
+
+	section, limit = processInput(strings.Repeat("a", 1000), "", 200)
+	require.LessOrEqual(t, len(section), 100)
+	require.Equal(t, 200-len(section), limit)
+	require.Contains(t, section, truncatedTextReplacement)
+
+	require.NotPanics(t, func() {
+		processInput(strings.Repeat("a", 1000), "", 10)
+	})
+}
@@ -0,0 +1,240 @@
+package run
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	stepStatusPending = "pending"
+	stepStatusRunning = "running"
+	stepStatusSuccess = "success"
+	stepStatusFailure = "failure"
+	stepStatusSkipped = "skipped"
+	stepHeaderFormat  = "<details%s>\n<summary>%s %s%s</summary>\n\n"
+	stepFooter        = "\n\n</details>\n"
+	stepSkippedText   = "_skipped_"
+)
+
+var stepIcons = map[string]string{
+	stepStatusPending: "⚪",
+	stepStatusRunning: "🔄",
+	stepStatusSuccess: "✅",
+	stepStatusFailure: "❌",
+	stepStatusSkipped: "⏭️",
+}
+
+// Step is a single named command of the steps file
+type Step struct {
+	Name    string   `json:"name"`
+	Command []string `json:"command"`
+}
+
+// stepState tracks the execution and the output of a step
+type stepState struct {
+	step       *Step
+	screen     *SyncScreen
+	lock       *sync.RWMutex
+	status     string
+	startedAt  time.Time
+	finishedAt time.Time
+}
+
+func readSteps(path string) ([]*stepState, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading steps file")
+	}
+
+	var steps []*Step
+	err = json.Unmarshal(content, &steps)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing steps file")
+	}
+
+	if len(steps) == 0 {
+		return nil, errors.New("steps file has no steps")
+	}
+
+	out := make([]*stepState, len(steps))
+	for i, s := range steps {
+		if s.Name == "" {
+			return nil, errors.Errorf("step %d has no name", i)
+		}
+		if len(s.Command) == 0 {
+			return nil, errors.Errorf("step %s has no command", s.Name)
+		}
+
+		screen, err := NewSyncScreen()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		out[i] = &stepState{
+			step:   s,
+			screen: screen,
+			lock:   &sync.RWMutex{},
+			status: stepStatusPending,
+		}
+	}
+
+	return out, nil
+}
+
+func (s *stepState) setStatus(status string, now time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.status = status
+	switch status {
+	case stepStatusRunning:
+		s.startedAt = now
+	case stepStatusSuccess, stepStatusFailure:
+		s.finishedAt = now
+	}
+}
+
+// header renders the opening of the collapsible section of the step
+func (s *stepState) header(now time.Time) string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	duration := ""
+	switch s.status {
+	case stepStatusRunning:
+		duration = fmt.Sprintf(" (%s)", formatDuration(now.Sub(s.startedAt)))
+	case stepStatusSuccess, stepStatusFailure:
+		duration = fmt.Sprintf(" (%s)", formatDuration(s.finishedAt.Sub(s.startedAt)))
+	}
+
+	// keeps the failing or running step expanded
+	open := ""
+	if s.status == stepStatusRunning || s.status == stepStatusFailure {
+		open = " open"
+	}
+
+	return fmt.Sprintf(stepHeaderFormat, open, stepIcons[s.status], s.step.Name, duration)
+}
+
+func (s *stepState) getStatus() string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.status
+}
+
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
+
+// runSteps runs the steps one after another under the same check run
+func (r *Run) runSteps() error {
+	r.startedAt = r.clock.Now()
+	err := r.createCheckRun("")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return errors.WithStack(r.monitor(func() error {
+		var firstErr error
+		for _, s := range r.steps {
+			if (firstErr != nil && !r.ContinueOnError) || r.budget.killed() {
+				s.setStatus(stepStatusSkipped, r.clock.Now())
+				continue
+			}
+
+			err := r.runStep(s)
+			if err != nil && firstErr == nil {
+				firstErr = errors.Wrapf(err, "error running step %s", s.step.Name)
+			}
+		}
+		return firstErr
+	}))
+}
+
+func (r *Run) runStep(s *stepState) error {
+	for _, w := range r.additionalWriters {
+		_, err := fmt.Fprintf(w, "==> %s\n", s.step.Name)
+		if err != nil {
+			return errors.Wrap(err, "error writing step header")
+		}
+	}
+
+	cmd := r.newCommand(s.step.Command)
+	screen, flooded := r.budgetedOutput(s.screen)
+	out, flush := r.maskedOutput(screen)
+
+	s.setStatus(stepStatusRunning, r.clock.Now())
+	wait, err := r.startCommand(cmd, out, flooded)
+	if err == nil {
+		err = wait()
+		flushErr := flush()
+		if err == nil {
+			err = flushErr
+		}
+	} else {
+		_, writeErr := s.screen.Write([]byte(r.masker.mask(fmt.Sprintf("error starting command %s: %v", strings.Join(s.step.Command, " "), err))))
+		if writeErr != nil {
+			return errors.Wrapf(writeErr, "Error writing update to command")
+		}
+	}
+
+	if err != nil {
+		s.setStatus(stepStatusFailure, r.clock.Now())
+		return errors.WithStack(err)
+	}
+
+	s.setStatus(stepStatusSuccess, r.clock.Now())
+	return nil
+}
+
+// processSteps renders every step into its own collapsible section, the output limit
+// is shared evenly among the steps having output
+func processSteps(steps []*stepState, highlight string, now time.Time, limit int) string {
+	headers := make([]string, len(steps))
+	texts := make([]string, len(steps))
+	hasOutput := make([]bool, len(steps))
+	budget := limit
+	withOutput := 0
+	for i, s := range steps {
+		headers[i] = s.header(now)
+		budget -= len(headers[i]) + len(stepFooter)
+
+		switch s.getStatus() {
+		case stepStatusPending:
+		case stepStatusSkipped:
+			texts[i] = stepSkippedText
+			budget -= len(stepSkippedText)
+		default:
+			if s.screen.Written() > 0 {
+				hasOutput[i] = true
+				withOutput++
+			}
+		}
+	}
+
+	share := budget
+	if withOutput > 0 {
+		share = budget / withOutput
+	}
+
+	builder := strings.Builder{}
+	for i, s := range steps {
+		builder.WriteString(headers[i])
+		text := texts[i]
+		if hasOutput[i] {
+			// the output is truncated to its end anyway, only the end of the screen is read
+			text = s.screen.ReadTail(share)
+			if text != "" {
+				text = processOutputWithLimit(text, highlight, share)
+			}
+		}
+		builder.WriteString(text)
+		builder.WriteString(stepFooter)
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,130 @@
+package run
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func writeSteps(t *testing.T, steps []*Step) string {
+	t.Helper()
+	content, err := json.Marshal(steps)
+	require.NoError(t, err)
+	f := filepath.Join(t.TempDir(), "steps.json")
+	require.NoError(t, os.WriteFile(f, content, 0644))
+	return f
+}
+
+func TestStepsStopAtFirstFailure(t *testing.T) {
+	r, _ := newRun(t, &runConfig{runId: 11, frequency: 5 * time.Second})
+	r.Steps = writeSteps(t, []*Step{
+		{Name: "first", Command: command(t, "echo", "first output")},
+		{Name: "second", Command: command(t, "errorm", "2", "second failed")},
+		{Name: "third", Command: command(t, "echo", "third output")},
+	})
+
+	err := r.Run(&Config{})
+	require.ErrorContains(t, err, "error running step second")
+
+	update := lastUpdate(t, r)
+	require.Equal(t, checksConclusionFailure, update.GetConclusion())
+	require.Equal(t, "<details>\n<summary>✅ first (0s)</summary>\n\n```bash\nfirst output\n```\n\n</details>\n"+
+		"<details open>\n<summary>❌ second (0s)</summary>\n\n```bash\nsecond failed\n```\n\n</details>\n"+
+		"<details>\n<summary>⏭️ third</summary>\n\n_skipped_\n\n</details>\n", update.GetOutput().GetText())
+}
+
+func TestStepsContinueOnError(t *testing.T) {
+	r, _ := newRun(t, &runConfig{runId: 12, frequency: 5 * time.Second})
+	r.ContinueOnError = true
+	r.Steps = writeSteps(t, []*Step{
+		{Name: "first", Command: command(t, "errorm", "2", "first failed")},
+		{Name: "second", Command: command(t, "echo", "second output")},
+	})
+
+	err := r.Run(&Config{})
+	require.ErrorContains(t, err, "error running step first")
+
+	update := lastUpdate(t, r)
+	require.Equal(t, checksConclusionFailure, update.GetConclusion())
+	require.Contains(t, update.GetOutput().GetText(), "<summary>✅ second (0s)</summary>\n\n```bash\nsecond output\n```")
+}
+
+func TestStepsAllSucceed(t *testing.T) {
+	r, _ := newRun(t, &runConfig{runId: 13, frequency: 5 * time.Second})
+	r.Steps = writeSteps(t, []*Step{
+		{Name: "first", Command: command(t, "echo", "1")},
+		{Name: "second", Command: command(t, "echo", "2")},
+	})
+
+	require.NoError(t, r.Run(&Config{}))
+	update := lastUpdate(t, r)
+	require.Equal(t, checksConclusionSuccess, update.GetConclusion())
+}
+
+func TestStepsShareOutputBudget(t *testing.T) {
+	r, _ := newRun(t, &runConfig{runId: 15, frequency: 5 * time.Second})
+	r.MaxOutputLines = 3
+	r.Steps = writeSteps(t, []*Step{
+		{Name: "first", Command: []string{"seq", "2"}},
+		{Name: "second", Command: []string{"seq", "3", "10"}},
+		{Name: "third", Command: []string{"seq", "11", "20"}},
+	})
+
+	require.NoError(t, r.Run(&Config{}))
+	update := lastUpdate(t, r)
+	text := update.GetOutput().GetText()
+	require.Contains(t, text, "```bash\n1\n2\n```")
+	require.Contains(t, text, "```bash\n3\n\n[log flood] the output exceeded 3 lines, the rest of it is not shown\n```")
+	require.NotContains(t, text, "11")
+}
+
+func TestStepsSkippedOnceKilledByOutputBudget(t *testing.T) {
+	r, _ := newRun(t, &runConfig{runId: 16, frequency: 5 * time.Second})
+	r.MaxOutputLines = 3
+	r.OnMaxOutput = outputFloodKill
+	r.ContinueOnError = true
+	r.Steps = writeSteps(t, []*Step{
+		{Name: "first", Command: []string{"seq", "1000000"}},
+		{Name: "second", Command: command(t, "echo", "second output")},
+	})
+
+	require.ErrorContains(t, r.Run(&Config{}), "error running step first")
+	update := lastUpdate(t, r)
+	require.Equal(t, checksConclusionFailure, update.GetConclusion())
+	require.Contains(t, update.GetOutput().GetText(), "<summary>⏭️ second</summary>\n\n_skipped_")
+}
+
+func TestStepsAndShellCommandAreExclusive(t *testing.T) {
+	r, _ := newRun(t, &runConfig{runId: 14}, command(t, "echo", "1")...)
+	r.Steps = writeSteps(t, []*Step{{Name: "first", Command: command(t, "echo", "1")}})
+	require.ErrorContains(t, r.Run(&Config{}), "cannot be used together")
+}
+
+func TestStepsRejectTeeStdin(t *testing.T) {
+	r, _ := newRun(t, &runConfig{runId: 17})
+	r.TeeStdin = true
+	r.Steps = writeSteps(t, []*Step{{Name: "first", Command: command(t, "echo", "1")}})
+	require.ErrorContains(t, r.Run(&Config{}), "--tee-stdin cannot be used with --steps")
+}
+
+func TestProcessStepsSharesOutputLimit(t *testing.T) {
+	t.Parallel()
+	steps := make([]*stepState, 3)
+	for i := range steps {
+		screen, err := NewSyncScreen()
+		require.NoError(t, err)
+		_, err = screen.Write([]byte(strings.Repeat("x", 40000)))
+		require.NoError(t, err)
+		steps[i] = &stepState{step: &Step{Name: "s"}, screen: screen, lock: &sync.RWMutex{}, status: stepStatusSuccess}
+	}
+
+	require.LessOrEqual(t, len(processSteps(steps, highlight, time.Now(), outputLimit)), outputLimit)
+	require.NotPanics(t, func() {
+		processSteps(steps, highlight, time.Now(), 10)
+	})
+}
@@ -0,0 +1,149 @@
+package run
+
+import (
+	"bytes"
+	"github.com/pkg/errors"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// summaryData is the data given to the summary templates
+type summaryData struct {
+	// Summary is the summary given with --summary
+	Summary string
+	// Command is the shell command, or the commands of the steps one per line
+	Command string
+	// Running tells whether the command is still running
+	Running bool
+	// Conclusion is the conclusion of the check run, empty while running
+	Conclusion string
+	// ExitCode is the exit code of the command, -1 while running or when it did not exit
+	ExitCode int
+	// ExitStatus is the exit status as shown in the execution footer, empty while running
+	ExitStatus string
+	// Duration is the time the command has been running for, rounded to the second
+	Duration time.Duration
+	// AnnotationCount is the number of annotations sent with the check run
+	AnnotationCount int
+	// Env holds the environment variables given with --summary-env, masked
+	Env map[string]string
+
+	// tail returns the end of the output, it is only read when the template asks for the last lines
+	tail func(n int) string
+}
+
+// LastLines returns the last n non empty lines of the output, among the ones a summary can hold
+func (d *summaryData) LastLines(n int) string {
+	if n <= 0 || d.tail == nil {
+		return ""
+	}
+	return lastLines(d.tail(summaryLimit), n)
+}
+
+// lastLines returns the last n non empty lines of the text
+func lastLines(text string, n int) string {
+	lines := make([]string, 0, max(n, 0))
+	for len(lines) < n && text != "" {
+		var line string
+		if i := strings.LastIndexByte(text, '\n'); i >= 0 {
+			text, line = text[:i], text[i+1:]
+		} else {
+			text, line = "", text
+		}
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	slices.Reverse(lines)
+	return strings.Join(lines, "\n")
+}
+
+// readTextOrFile returns the content of the file at value if there is one, value itself otherwise
+func readTextOrFile(value string) (string, error) {
+	_, err := os.Stat(value)
+	if err != nil {
+		return value, nil
+	}
+
+	content, err := os.ReadFile(value)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	return string(content), nil
+}
+
+// parseSummaryTemplate parses the summary template, either a fixed string or a file, nil when there is none
+func parseSummaryTemplate(tmpl string) (*template.Template, error) {
+	if tmpl == "" {
+		return nil, nil
+	}
+
+	content, err := readTextOrFile(tmpl)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading summary template file")
+	}
+
+	t, err := template.New("summary").Option("missingkey=zero").Parse(content)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing summary template")
+	}
+
+	return t, nil
+}
+
+// renderSummary renders the summary template with the state of the execution
+func (r *Run) renderSummary(t *template.Template, summary string, conclusion string, annotationCount int) (string, error) {
+	out := &bytes.Buffer{}
+	err := t.Execute(out, r.summaryData(summary, conclusion, annotationCount))
+	if err != nil {
+		return "", errors.Wrap(err, "error rendering summary template")
+	}
+
+	return out.String(), nil
+}
+
+func (r *Run) summaryData(summary string, conclusion string, annotationCount int) *summaryData {
+	data := &summaryData{
+		Summary:         summary,
+		Conclusion:      conclusion,
+		ExitCode:        -1,
+		AnnotationCount: annotationCount,
+		Env:             make(map[string]string, len(r.SummaryEnv)),
+		tail:            r.tailText,
+	}
+
+	for _, k := range r.SummaryEnv {
+		data.Env[k] = r.masker.mask(os.Getenv(k))
+	}
+
+	if len(r.steps) > 0 {
+		commands := make([]string, 0, len(r.steps))
+		for _, s := range r.steps {
+			commands = append(commands, strings.Join(s.step.Command, " "))
+		}
+		data.Command = strings.Join(commands, "\n")
+	} else {
+		data.Command = strings.Join(r.ShellCommand, " ")
+	}
+
+	r.resultLock.RLock()
+	defer r.resultLock.RUnlock()
+	end := r.finishedAt
+	if end.IsZero() {
+		data.Running = true
+		end = r.clock.Now()
+	} else {
+		data.ExitCode = r.exitCode
+		data.ExitStatus = r.exitStatus
+	}
+	if !r.startedAt.IsZero() {
+		data.Duration = end.Sub(r.startedAt).Round(time.Second)
+	}
+
+	return data
+}
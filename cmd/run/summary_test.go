@@ -0,0 +1,85 @@
+package run
+
+import (
+	"github.com/google/go-github/v64/github"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSummaryTemplates(t *testing.T) {
+	t.Parallel()
+	r, _ := newSampleRun(t, command(t, "errorm", "3", "first", "last")...)
+	r.NoExecutionFooter = true
+	r.SummaryTemplate = "{{.Summary}}: running for {{.Duration}}"
+	r.FinalSummaryTemplate = "{{.Conclusion}} with {{.ExitCode}} ({{.AnnotationCount}} annotations)\n{{.LastLines 1}}"
+
+	require.Error(t, r.Run(&Config{}))
+	runs := getCheckServiceOutFromRun(t, r).Calls()
+	created := runs[0].Options.(github.CreateCheckRunOptions)
+	require.Equal(t, sampleSummary+": running for 0s", created.GetOutput().GetSummary())
+	completed := runs[len(runs)-1].Options.(github.UpdateCheckRunOptions)
+	require.Equal(t, "failure with 3 (0 annotations)\nlast", completed.GetOutput().GetSummary())
+}
+
+func TestSummaryTemplateFile(t *testing.T) {
+	t.Parallel()
+	tmpl := filepath.Join(t.TempDir(), "summary.tmpl")
+	require.NoError(t, os.WriteFile(tmpl, []byte(`{{if .Running}}running{{else}}{{.ExitStatus}} {{.Env.PATH}}{{.Env.HOME}}{{end}}`), 0644))
+
+	r, _ := newSampleRun(t, command(t, "echo", "template")...)
+	r.NoExecutionFooter = true
+	r.SummaryTemplate = tmpl
+	r.SummaryEnv = []string{"PATH"}
+
+	require.NoError(t, r.Run(&Config{}))
+	runs := getCheckServiceOutFromRun(t, r).Calls()
+	created := runs[0].Options.(github.CreateCheckRunOptions)
+	require.Equal(t, "running", created.GetOutput().GetSummary())
+	completed := runs[len(runs)-1].Options.(github.UpdateCheckRunOptions)
+	require.Equal(t, "0 "+os.Getenv("PATH"), completed.GetOutput().GetSummary())
+}
+
+func TestSummaryTemplateInvalid(t *testing.T) {
+	t.Parallel()
+	r, _ := newSampleRun(t, command(t, "echo", "invalid")...)
+	r.SummaryTemplate = "{{.Unknown"
+
+	require.ErrorContains(t, r.Run(&Config{}), "error parsing summary template")
+	require.Empty(t, getCheckServiceOutFromRun(t, r).Calls())
+
+	r, _ = newSampleRun(t, command(t, "echo", "invalid")...)
+	r.FinalSummaryTemplate = "{{end}}"
+	require.ErrorContains(t, r.Run(&Config{}), "invalid final summary template")
+	require.Empty(t, getCheckServiceOutFromRun(t, r).Calls())
+}
+
+func TestLastLines(t *testing.T) {
+	t.Parallel()
+	read := 0
+	data := &summaryData{tail: func(n int) string {
+		read++
+		return "first\n\nsecond\n  \nthird\n\n"
+	}}
+	require.Equal(t, "second\nthird", data.LastLines(2))
+	require.Equal(t, "first\nsecond\nthird", data.LastLines(10))
+	require.Empty(t, data.LastLines(0))
+	require.Empty(t, data.LastLines(-1))
+	require.Equal(t, 2, read)
+	require.Empty(t, (&summaryData{}).LastLines(3))
+}
+
+func TestSummaryEnvMasked(t *testing.T) {
+	t.Setenv("CHECKS4SHELL_TEST_TOKEN", "secret-value")
+	t.Setenv("CHECKS4SHELL_TEST_OTHER", "other-value")
+	r, _ := newSampleRun(t, command(t, "echo", "env")...)
+	r.NoExecutionFooter = true
+	r.SummaryTemplate = "{{.Env.CHECKS4SHELL_TEST_TOKEN}}|{{.Env.CHECKS4SHELL_TEST_OTHER}}"
+	r.SummaryEnv = []string{"CHECKS4SHELL_TEST_TOKEN"}
+	r.Mask = []string{"secret-value"}
+
+	require.NoError(t, r.Run(&Config{}))
+	completed := lastUpdate(t, r)
+	require.Equal(t, "***|", completed.GetOutput().GetSummary())
+}
@@ -0,0 +1,112 @@
+package run
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	usageFooterFormat       = "\n📊 Max RSS: `%s` · CPU: `%s` user, `%s` system · Context switches: `%d` voluntary, `%d` involuntary"
+	maxRSSExceededFormat    = " (limit of `%s` exceeded)"
+	checksConclusionNeutral = "neutral"
+)
+
+// resourceUsage is what the command used of the resources of the host, summed over the steps but for the
+// max RSS which is the largest of them
+type resourceUsage struct {
+	MaxRSS              int64
+	User                time.Duration
+	System              time.Duration
+	VoluntarySwitches   int64
+	InvoluntarySwitches int64
+}
+
+// add adds the usage of a process to the total
+func (u *resourceUsage) add(other *resourceUsage) {
+	u.MaxRSS = max(u.MaxRSS, other.MaxRSS)
+	u.User += other.User
+	u.System += other.System
+	u.VoluntarySwitches += other.VoluntarySwitches
+	u.InvoluntarySwitches += other.InvoluntarySwitches
+}
+
+// recordUsage adds the resources used by the finished process to those of the run
+func (r *Run) recordUsage(state *os.ProcessState) {
+	usage := processUsage(state)
+	if usage == nil {
+		return
+	}
+
+	r.resultLock.Lock()
+	defer r.resultLock.Unlock()
+	if r.usage == nil {
+		r.usage = &resourceUsage{}
+	}
+	r.usage.add(usage)
+}
+
+// usageFooter returns the resources used by the command, it is called with the result lock held
+func (r *Run) usageFooter() string {
+	if r.usage == nil || r.NoResourceUsage {
+		return ""
+	}
+
+	footer := fmt.Sprintf(usageFooterFormat, formatBytes(r.usage.MaxRSS), formatDuration(r.usage.User),
+		formatDuration(r.usage.System), r.usage.VoluntarySwitches, r.usage.InvoluntarySwitches)
+	if r.maxRSS > 0 && r.usage.MaxRSS > r.maxRSS {
+		footer += fmt.Sprintf(maxRSSExceededFormat, formatBytes(r.maxRSS))
+	}
+	return footer
+}
+
+// maxRSSExceeded tells whether the command used more memory than --max-rss, returning the max RSS it used
+func (r *Run) maxRSSExceeded() (int64, bool) {
+	r.resultLock.RLock()
+	defer r.resultLock.RUnlock()
+	if r.maxRSS <= 0 || r.usage == nil {
+		return 0, false
+	}
+	return r.usage.MaxRSS, r.usage.MaxRSS > r.maxRSS
+}
+
+var byteUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"G", 1 << 30},
+	{"M", 1 << 20},
+	{"K", 1 << 10},
+}
+
+// parseBytes parses a size in bytes, with an optional K, M or G suffix in powers of 1024
+func parseBytes(size string) (int64, error) {
+	text := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(size)), "B")
+	multiplier := int64(1)
+	for _, u := range byteUnits {
+		if strings.HasSuffix(text, u.suffix) {
+			text = strings.TrimSuffix(text, u.suffix)
+			multiplier = u.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(text, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, errors.Errorf("invalid size %q, expected a number of bytes with an optional K, M or G suffix", size)
+	}
+	return n * multiplier, nil
+}
+
+// formatBytes formats a size in bytes with the largest unit it has at least one of
+func formatBytes(size int64) string {
+	for _, u := range byteUnits {
+		if size >= u.size {
+			return fmt.Sprintf("%.1f %siB", float64(size)/float64(u.size), u.suffix)
+		}
+	}
+	return fmt.Sprintf("%d B", size)
+}
@@ -0,0 +1,8 @@
+//go:build darwin
+
+package run
+
+// maxRSSBytes converts the max RSS of rusage to bytes, darwin reporting it in bytes already
+func maxRSSBytes(maxrss int64) int64 {
+	return maxrss
+}
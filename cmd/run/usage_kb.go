@@ -0,0 +1,8 @@
+//go:build unix && !darwin
+
+package run
+
+// maxRSSBytes converts the max RSS of rusage to bytes, linux and the BSDs reporting it in kilobytes
+func maxRSSBytes(maxrss int64) int64 {
+	return maxrss * 1024
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package run
+
+import "os"
+
+func processUsage(*os.ProcessState) *resourceUsage {
+	return nil
+}
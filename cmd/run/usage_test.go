@@ -0,0 +1,76 @@
+package run
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseBytes(t *testing.T) {
+	t.Parallel()
+	for size, expected := range map[string]int64{
+		"512":   512,
+		"64K":   64 * 1024,
+		"512MB": 512 * 1024 * 1024,
+		"2g":    2 * 1024 * 1024 * 1024,
+	} {
+		n, err := parseBytes(size)
+		require.NoError(t, err, size)
+		require.Equal(t, expected, n, size)
+	}
+
+	for _, size := range []string{"", "M", "-1K", "12T"} {
+		_, err := parseBytes(size)
+		require.Error(t, err, size)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, "12 B", formatBytes(12))
+	require.Equal(t, "1.5 KiB", formatBytes(1536))
+	require.Equal(t, "512.0 MiB", formatBytes(512*1024*1024))
+}
+
+func TestResourceUsage(t *testing.T) {
+	t.Parallel()
+	r, _ := newSampleRun(t, command(t, "echo", "measured")...)
+	r.NoResourceUsage = false
+
+	require.NoError(t, r.Run(&Config{}))
+	completed := lastUpdate(t, r)
+	require.Contains(t, completed.GetOutput().GetSummary(), "📊 Max RSS: `")
+	require.Greater(t, r.usage.MaxRSS, int64(1024*1024))
+}
+
+func TestMaxRSS(t *testing.T) {
+	t.Parallel()
+	r, _ := newSampleRun(t, command(t, "echo", "measured")...)
+	r.NoResourceUsage = false
+	r.MaxRSS = "1K"
+
+	require.ErrorContains(t, r.Run(&Config{}), "exceeded the limit of 1.0 KiB")
+	completed := lastUpdate(t, r)
+	require.Equal(t, checksConclusionFailure, completed.GetConclusion())
+	require.Contains(t, completed.GetOutput().GetSummary(), "(limit of `1.0 KiB` exceeded)")
+}
+
+func TestMaxRSSNeutral(t *testing.T) {
+	t.Parallel()
+	r, _ := newSampleRun(t, command(t, "echo", "measured")...)
+	r.MaxRSS = "1K"
+	r.MaxRSSConclusion = checksConclusionNeutral
+
+	require.NoError(t, r.Run(&Config{}))
+	completed := lastUpdate(t, r)
+	require.Equal(t, checksConclusionNeutral, completed.GetConclusion())
+}
+
+func TestMaxRSSNotExceeded(t *testing.T) {
+	t.Parallel()
+	r, _ := newSampleRun(t, command(t, "echo", "measured")...)
+	r.MaxRSS = "64G"
+
+	require.NoError(t, r.Run(&Config{}))
+	completed := lastUpdate(t, r)
+	require.Equal(t, checksConclusionSuccess, completed.GetConclusion())
+}
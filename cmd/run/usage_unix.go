@@ -0,0 +1,29 @@
+//go:build unix
+
+package run
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// processUsage returns the resources used by the finished process, nil when unknown
+func processUsage(state *os.ProcessState) *resourceUsage {
+	if state == nil {
+		return nil
+	}
+
+	rusage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok || rusage == nil {
+		return nil
+	}
+
+	return &resourceUsage{
+		MaxRSS:              maxRSSBytes(int64(rusage.Maxrss)),
+		User:                time.Duration(rusage.Utime.Nano()),
+		System:              time.Duration(rusage.Stime.Nano()),
+		VoluntarySwitches:   int64(rusage.Nvcsw),
+		InvoluntarySwitches: int64(rusage.Nivcsw),
+	}
+}
@@ -0,0 +1,37 @@
+package run
+
+import (
+	"fmt"
+	"github.com/google/go-github/v64/github"
+	"net/http"
+)
+
+// webhookHandler verifies the signature of GitHub webhook deliveries and
+// hands the parsed events over to handle, which returns the response status and message
+type webhookHandler struct {
+	secret []byte
+	handle func(event any) (int, string)
+}
+
+func (h *webhookHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	payload, err := github.ValidatePayload(req, h.secret)
+	if err != nil {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	event, err := github.ParseWebHook(github.WebHookType(req), payload)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error parsing payload: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	status, msg := h.handle(event)
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintln(w, msg)
+}
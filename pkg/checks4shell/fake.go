@@ -0,0 +1,15 @@
+package checks4shell
+
+import (
+	"github.com/block/checks4shell/cmd/run"
+	"github.com/coder/quartz"
+)
+
+// FakeChecksService keeps the check runs in memory instead of sending them to GitHub, enforcing the limits of
+// GitHub all the same. CheckRuns returns them once the commands ran
+type FakeChecksService = run.MockChecksService
+
+// NewFakeChecksService returns an empty fake checks service, timestamping the check runs with the clock
+func NewFakeChecksService(clock quartz.Clock) *FakeChecksService {
+	return run.NewMockChecksService(clock)
+}
@@ -0,0 +1,189 @@
+// Package checks4shell runs shell commands filling GitHub check runs with their output,
+// for programs embedding checks4shell rather than calling its command line
+package checks4shell
+
+import (
+	"context"
+	"github.com/block/checks4shell/cmd/run"
+	"github.com/coder/quartz"
+	"github.com/google/go-github/v64/github"
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"syscall"
+	"time"
+)
+
+const defaultUpdateFrequency = 5 * time.Second
+
+// ChecksService is the part of the GitHub checks API the runner uses
+type ChecksService = run.ChecksService
+
+// NewChecksService returns the checks service of the GitHub client
+func NewChecksService(client *github.Client) ChecksService {
+	return run.NewChecksService(client)
+}
+
+// Check describes the check run filled with the output of a command
+type Check struct {
+	Owner           string
+	Repository      string
+	CommitSHA       string
+	Name            string
+	Title           string
+	Summary         string
+	DetailsURL      string
+	ExternalID      string
+	SyntaxHighlight string
+}
+
+// Limits are the lengths the output of the check run is truncated to, the GitHub limits when left to zero
+type Limits struct {
+	Output  int
+	Summary int
+}
+
+// Option configures a Runner
+type Option func(*Runner)
+
+// WithChecksService sends the check runs to the given service, nothing is sent without one
+func WithChecksService(service ChecksService) Option {
+	return func(r *Runner) {
+		r.service = service
+	}
+}
+
+// WithClock drives the updates of the check runs with the given clock, the real one by default
+func WithClock(clock quartz.Clock) Option {
+	return func(r *Runner) {
+		r.clock = clock
+	}
+}
+
+// WithWriters gives the output of the commands to the writers along with the check runs
+func WithWriters(writers ...io.Writer) Option {
+	return func(r *Runner) {
+		r.writers = writers
+	}
+}
+
+// WithLimits truncates the output of the check runs to the given limits
+func WithLimits(limits Limits) Option {
+	return func(r *Runner) {
+		r.limits = limits
+	}
+}
+
+// WithUpdateFrequency updates the check runs at the given frequency while the commands run, every 5 seconds by default
+func WithUpdateFrequency(frequency time.Duration) Option {
+	return func(r *Runner) {
+		r.updateFrequency = frequency
+	}
+}
+
+// WithKillGracePeriod gives the commands interrupted the given time to exit before they are killed, 5 seconds by
+// default
+func WithKillGracePeriod(period time.Duration) Option {
+	return func(r *Runner) {
+		r.killGracePeriod = period
+	}
+}
+
+// Runner runs commands, each filling a check run with its output
+type Runner struct {
+	service         ChecksService
+	clock           quartz.Clock
+	writers         []io.Writer
+	limits          Limits
+	updateFrequency time.Duration
+	killGracePeriod time.Duration
+}
+
+// NewRunner returns a runner configured with the options
+func NewRunner(opts ...Option) *Runner {
+	r := &Runner{
+		clock:           quartz.NewReal(),
+		updateFrequency: defaultUpdateFrequency,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Run runs the command, creating the check run and updating it until the command finishes. The command is
+// interrupted when the context is done, and killed when it is still running once the kill grace period passed, in
+// which case the error of the context is returned. The error returned wraps an *exec.ExitError when the command fails
+func (r *Runner) Run(ctx context.Context, check Check, command ...string) error {
+	if len(command) == 0 {
+		return errors.New("a command is required")
+	}
+
+	// the second signal, sent once the kill grace period passed, kills the command
+	c := &run.Run{
+		Owner:           check.Owner,
+		Repository:      check.Repository,
+		CommitSHA:       check.CommitSHA,
+		Name:            check.Name,
+		Title:           check.Title,
+		Summary:         check.Summary,
+		DetailsURL:      check.DetailsURL,
+		ExternalID:      check.ExternalID,
+		SyntaxHighlight: check.SyntaxHighlight,
+		UpdateFrequency: r.updateFrequency,
+		KillGracePeriod: r.killGracePeriod,
+		SecondSignal:    "kill",
+		ShellCommand:    command,
+	}
+
+	signals := make(chan os.Signal, 1)
+	err := c.Init(run.Options{
+		ChecksService: r.service,
+		Clock:         r.clock,
+		Writers:       r.writers,
+		OutputLimit:   r.limits.Output,
+		SummaryLimit:  r.limits.Summary,
+		Signals:       signals,
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	finished := make(chan struct{})
+	go r.interrupt(ctx, c.KillGracePeriod, signals, finished)
+	err = c.Run(&run.Config{})
+	close(finished)
+	if ctx.Err() != nil {
+		return errors.WithStack(ctx.Err())
+	}
+
+	return errors.WithStack(err)
+}
+
+// interrupt interrupts the command once the context is done, and terminates it when the grace period passed
+func (r *Runner) interrupt(ctx context.Context, grace time.Duration, signals chan<- os.Signal, finished <-chan struct{}) {
+	select {
+	case <-ctx.Done():
+	case <-finished:
+		return
+	}
+
+	select {
+	case signals <- os.Interrupt:
+	case <-finished:
+		return
+	}
+
+	timer := r.clock.NewTimer(grace, "runner", "kill-grace")
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-finished:
+		return
+	}
+
+	select {
+	case signals <- syscall.SIGTERM:
+	case <-finished:
+	}
+}
@@ -0,0 +1,144 @@
+package checks4shell_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/block/checks4shell/pkg/checks4shell"
+	"github.com/coder/quartz"
+	"github.com/stretchr/testify/require"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+var sampleCheck = checks4shell.Check{
+	Owner:      "owner",
+	Repository: "repo",
+	CommitSHA:  "sha",
+	Name:       "test",
+	Title:      "Tests",
+	Summary:    "summary",
+}
+
+func TestRunner(t *testing.T) {
+	t.Parallel()
+	service := checks4shell.NewFakeChecksService(quartz.NewReal())
+	out := &bytes.Buffer{}
+	runner := checks4shell.NewRunner(
+		checks4shell.WithChecksService(service),
+		checks4shell.WithWriters(out),
+		checks4shell.WithUpdateFrequency(time.Hour),
+	)
+
+	require.NoError(t, runner.Run(context.Background(), sampleCheck, "echo", "hello"))
+	require.Equal(t, "hello\n", out.String())
+	checkRuns := service.CheckRuns()
+	require.Len(t, checkRuns, 1)
+	require.Equal(t, "test", checkRuns[0].GetName())
+	require.Equal(t, "completed", checkRuns[0].GetStatus())
+	require.Equal(t, "success", checkRuns[0].GetConclusion())
+	require.Equal(t, "Tests", checkRuns[0].GetOutput().GetTitle())
+	require.Equal(t, "```\nhello\n```", checkRuns[0].GetOutput().GetText())
+}
+
+func TestRunnerLimits(t *testing.T) {
+	t.Parallel()
+	service := checks4shell.NewFakeChecksService(quartz.NewReal())
+	runner := checks4shell.NewRunner(
+		checks4shell.WithChecksService(service),
+		checks4shell.WithLimits(checks4shell.Limits{Output: 40, Summary: 100}),
+	)
+
+	require.NoError(t, runner.Run(context.Background(), sampleCheck, "seq", "1000"))
+	checkRuns := service.CheckRuns()
+	require.Len(t, checkRuns, 1)
+	require.LessOrEqual(t, len(checkRuns[0].GetOutput().GetText()), 40)
+	require.LessOrEqual(t, len(checkRuns[0].GetOutput().GetSummary()), 100)
+}
+
+func TestRunnerSmallLimits(t *testing.T) {
+	t.Parallel()
+	service := checks4shell.NewFakeChecksService(quartz.NewReal())
+	runner := checks4shell.NewRunner(
+		checks4shell.WithChecksService(service),
+		checks4shell.WithLimits(checks4shell.Limits{Output: 10, Summary: 10}),
+	)
+
+	require.NoError(t, runner.Run(context.Background(), sampleCheck, "seq", "1000"))
+	checkRuns := service.CheckRuns()
+	require.Len(t, checkRuns, 1)
+	require.LessOrEqual(t, len(checkRuns[0].GetOutput().GetText()), 10)
+	require.LessOrEqual(t, len(checkRuns[0].GetOutput().GetSummary()), 10)
+}
+
+func TestRunnerFailure(t *testing.T) {
+	t.Parallel()
+	service := checks4shell.NewFakeChecksService(quartz.NewReal())
+	runner := checks4shell.NewRunner(checks4shell.WithChecksService(service))
+
+	err := runner.Run(context.Background(), sampleCheck, "sh", "-c", "exit 3")
+	var exitErr *exec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	require.Equal(t, 3, exitErr.ExitCode())
+	require.Equal(t, "failure", service.CheckRuns()[0].GetConclusion())
+}
+
+func TestRunnerInterruptedByContext(t *testing.T) {
+	t.Parallel()
+	service := checks4shell.NewFakeChecksService(quartz.NewReal())
+	runner := checks4shell.NewRunner(checks4shell.WithChecksService(service))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	require.Error(t, runner.Run(ctx, sampleCheck, "sleep", "10"))
+	require.Equal(t, "failure", service.CheckRuns()[0].GetConclusion())
+}
+
+// cancelOnOutput cancels the context once the output contains the text
+type cancelOnOutput struct {
+	text   string
+	cancel context.CancelFunc
+	out    bytes.Buffer
+}
+
+func (c *cancelOnOutput) Write(p []byte) (int, error) {
+	c.out.Write(p)
+	if strings.Contains(c.out.String(), c.text) {
+		c.cancel()
+	}
+	return len(p), nil
+}
+
+func TestRunnerKillsCommandIgnoringInterrupt(t *testing.T) {
+	t.Parallel()
+	service := checks4shell.NewFakeChecksService(quartz.NewReal())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runner := checks4shell.NewRunner(
+		checks4shell.WithChecksService(service),
+		checks4shell.WithWriters(&cancelOnOutput{text: "ready", cancel: cancel}),
+		checks4shell.WithKillGracePeriod(100*time.Millisecond),
+	)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- runner.Run(ctx, sampleCheck, "sh", "-c", "trap '' INT; echo ready; sleep 30")
+	}()
+	select {
+	case err := <-done:
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(10 * time.Second):
+		t.Fatal("the command ignoring the interrupt was never killed")
+	}
+	require.Equal(t, "failure", service.CheckRuns()[0].GetConclusion())
+}
+
+func TestRunnerWithoutChecksService(t *testing.T) {
+	t.Parallel()
+	out := &bytes.Buffer{}
+	runner := checks4shell.NewRunner(checks4shell.WithWriters(out))
+
+	require.NoError(t, runner.Run(context.Background(), sampleCheck, "echo", "offline"))
+	require.Equal(t, "offline\n", out.String())
+}